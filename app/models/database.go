@@ -2,10 +2,33 @@ package models
 
 // Trigger 表示一个数据库触发器
 type Trigger struct {
-	Name       string `json:"name"`
-	Table      string `json:"table"`
-	Type       string `json:"type"`   // INSERT, UPDATE, DELETE
-	Timing     string `json:"timing"` // BEFORE, AFTER, INSTEAD OF
-	Definition string `json:"definition"`
-	SQL        string `json:"sql"`
+	Name       string   `json:"name"`
+	Table      string   `json:"table"`
+	Type       string   `json:"type"`              // INSERT, UPDATE, DELETE
+	Timing     string   `json:"timing"`            // BEFORE, AFTER, INSTEAD OF
+	Columns    []string `json:"columns,omitempty"` // UPDATE OF 的列
+	When       string   `json:"when,omitempty"`    // WHEN 条件
+	Body       string   `json:"body"`              // BEGIN ... END 之间的语句
+	Definition string   `json:"definition"`
+	SQL        string   `json:"sql"`
+	Disabled   bool     `json:"disabled"` // 已停用, 定义保存在附属存储中
+}
+
+// CreateTriggerRequest 创建触发器的请求体, 传 sql 时直接使用原始语句, 否则按各字段生成
+type CreateTriggerRequest struct {
+	SQL     string   `json:"sql,omitempty"`
+	Name    string   `json:"name" validate:"required_without=SQL"`
+	Table   string   `json:"table" validate:"required_without=SQL"`
+	Timing  string   `json:"timing,omitempty" validate:"omitempty,oneof=BEFORE AFTER 'INSTEAD OF'"`
+	Event   string   `json:"event" validate:"omitempty,oneof=INSERT UPDATE DELETE"`
+	Columns []string `json:"columns,omitempty"` // 仅 UPDATE 可用
+	When    string   `json:"when,omitempty"`
+	Body    string   `json:"body" validate:"required_without=SQL"` // 一条或多条语句
+}
+
+// CreateViewRequest 创建或修改视图的请求体
+type CreateViewRequest struct {
+	Name    string   `json:"name" validate:"required"`
+	Columns []string `json:"columns,omitempty"`       // 可选的列名列表
+	SQL     string   `json:"sql" validate:"required"` // SELECT 语句
 }
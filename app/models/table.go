@@ -1,33 +1,90 @@
 package models
 
-// CreateTableRequest 创建表的请求体
+// CreateTableRequest 创建表的请求体, 不传 columns 时创建只有自增 id 的表
 type CreateTableRequest struct {
-	TableName string `json:"tableName" validate:"required"`
+	TableName    string        `json:"tableName" validate:"required"`
+	Columns      []TableColumn `json:"columns,omitempty" validate:"dive"`
+	PrimaryKey   []string      `json:"primaryKey,omitempty"` // 复合主键, 与列上的 primary 互斥
+	Uniques      [][]string    `json:"uniques,omitempty"`    // 表级 UNIQUE 约束, 每项为一组列
+	Checks       []string      `json:"checks,omitempty"`     // 表级 CHECK 表达式
+	ForeignKeys  []ForeignKey  `json:"foreignKeys,omitempty" validate:"dive"`
+	Strict       bool          `json:"strict,omitempty"`       // STRICT 表, 需要 SQLite 3.37+
+	WithoutRowID bool          `json:"withoutRowid,omitempty"` // WITHOUT ROWID 表, 必须有主键
 }
 
 // TableColumn 表示一个列的定义
 type TableColumn struct {
-	Name    string  `json:"name" validate:"required"`
-	Type    string  `json:"type" validate:"required,oneof=TEXT INTEGER REAL BLOB"` // 白名单类型
-	Primary bool    `json:"primary,omitempty"`
-	NotNull bool    `json:"notNull,omitempty"`
-	Unique  bool    `json:"unique,omitempty"`
-	Default *string `json:"default,omitempty"` // 支持 NULL 默认值
+	Name          string  `json:"name" validate:"required"`
+	Type          string  `json:"type" validate:"required,oneof=TEXT INTEGER REAL BLOB NUMERIC ANY"` // 白名单类型
+	Primary       bool    `json:"primary,omitempty"`
+	AutoIncrement bool    `json:"autoIncrement,omitempty"` // 仅 INTEGER PRIMARY KEY 可用
+	NotNull       bool    `json:"notNull,omitempty"`
+	Unique        bool    `json:"unique,omitempty"`
+	Default       *string `json:"default,omitempty"` // 支持 NULL 默认值
+	Check         string  `json:"check,omitempty"`   // 列级 CHECK 表达式
+	Collate       string  `json:"collate,omitempty" validate:"omitempty,oneof=BINARY NOCASE RTRIM"`
+}
+
+// ForeignKey 表示一个外键约束
+type ForeignKey struct {
+	Columns    []string `json:"columns" validate:"required,min=1"`
+	RefTable   string   `json:"refTable" validate:"required"`
+	RefColumns []string `json:"refColumns,omitempty"` // 不传时引用被引用表的主键
+	OnDelete   string   `json:"onDelete,omitempty" validate:"omitempty,oneof='NO ACTION' RESTRICT 'SET NULL' 'SET DEFAULT' CASCADE"`
+	OnUpdate   string   `json:"onUpdate,omitempty" validate:"omitempty,oneof='NO ACTION' RESTRICT 'SET NULL' 'SET DEFAULT' CASCADE"`
 }
 
 // TableInfo 表示一张表的完整结构信息
 type TableInfo struct {
-	Columns  []ColumnInfo  `json:"columns"`  // 字段信息
-	Indexes  []IndexInfo   `json:"indexes"`  // 索引信息
-	Triggers []TriggerInfo `json:"triggers"` // 触发器信息
+	Type         string           `json:"type"`         // table 或 view, 视图只读
+	Columns      []ColumnInfo     `json:"columns"`      // 字段信息
+	Indexes      []IndexInfo      `json:"indexes"`      // 索引信息
+	Triggers     []TriggerInfo    `json:"triggers"`     // 触发器信息
+	ForeignKeys  []ForeignKeyInfo `json:"foreignKeys"`  // 本表的外键
+	ReferencedBy []ForeignKeyInfo `json:"referencedBy"` // 其他表指向本表的外键
+	Constraints  []Constraint     `json:"constraints"`  // 表级约束
+	Options      []string         `json:"options"`      // 表选项, 如 STRICT、WITHOUT ROWID
+}
+
+// Constraint 列定义或表定义中的一个约束, 从 CREATE TABLE 语句解析
+type Constraint struct {
+	Name    string   `json:"name,omitempty"`    // CONSTRAINT 指定的名称
+	Type    string   `json:"type"`              // PRIMARY KEY / NOT NULL / NULL / UNIQUE / CHECK / DEFAULT / COLLATE / REFERENCES / GENERATED / FOREIGN KEY
+	Columns []string `json:"columns,omitempty"` // 表级 PRIMARY KEY / UNIQUE / FOREIGN KEY 的列
+	Expr    string   `json:"expr,omitempty"`    // CHECK / DEFAULT / GENERATED 的表达式, COLLATE 的排序规则
+	SQL     string   `json:"sql"`               // 约束原文
+}
+
+// ForeignKeyInfo 表示一个外键约束, 来自 PRAGMA foreign_key_list
+type ForeignKeyInfo struct {
+	ID         int      `json:"id"`         // 外键在所属表中的 id
+	Table      string   `json:"table"`      // 外键所属的表
+	Columns    []string `json:"columns"`    // 本表的列
+	RefTable   string   `json:"refTable"`   // 被引用的表
+	RefColumns []string `json:"refColumns"` // 被引用的列, 未声明时为被引用表的主键
+	OnDelete   string   `json:"onDelete"`
+	OnUpdate   string   `json:"onUpdate"`
+	Match      string   `json:"match"`
 }
 
 // IndexInfo 表示索引信息
 type IndexInfo struct {
-	Name    string   `json:"name"`    // 索引名
-	Unique  bool     `json:"unique"`  // 是否唯一
-	SQL     string   `json:"sql"`     // 创建语句
-	Columns []string `json:"columns"` // 索引包含的列（需额外查询）
+	Name    string        `json:"name"`            // 索引名
+	Unique  bool          `json:"unique"`          // 是否唯一
+	SQL     string        `json:"sql"`             // 创建语句
+	Columns []string      `json:"columns"`         // 索引包含的列（需额外查询）, 表达式列为表达式文本
+	Origin  string        `json:"origin"`          // c: CREATE INDEX, u: UNIQUE 约束, pk: PRIMARY KEY 约束
+	Partial bool          `json:"partial"`         // 是否为部分索引
+	Where   string        `json:"where,omitempty"` // 部分索引的条件
+	Items   []IndexColumn `json:"items"`           // 各索引项的详细信息
+}
+
+// IndexColumn 索引中的一项: 列或表达式
+type IndexColumn struct {
+	Column  string `json:"column,omitempty"` // 列名, 表达式项为空
+	Expr    string `json:"expr,omitempty"`   // 表达式, 仅表达式项有效
+	Desc    bool   `json:"desc"`             // 是否降序
+	Collate string `json:"collate"`          // 排序规则
 }
 
 // TriggerInfo 表示触发器信息
@@ -47,6 +104,13 @@ type ColumnInfo struct {
 	Default       string `json:"default"`       // 默认值（字符串形式）
 	Primary       bool   `json:"primary"`       // 是否为主键
 	AutoIncrement bool   `json:"autoIncrement"` // 是否为自增
+
+	Hidden      int          `json:"hidden"`                // PRAGMA table_xinfo 的 hidden: 0 普通列, 1 虚拟表隐藏列, 2 VIRTUAL 生成列, 3 STORED 生成列
+	Generated   string       `json:"generated,omitempty"`   // 生成列类型 VIRTUAL / STORED
+	Expression  string       `json:"expression,omitempty"`  // 生成列表达式
+	Collate     string       `json:"collate,omitempty"`     // 排序规则
+	Checks      []string     `json:"checks,omitempty"`      // 列级 CHECK 表达式
+	Constraints []Constraint `json:"constraints,omitempty"` // 列定义中的全部约束
 }
 
 // 导出表格数据参数
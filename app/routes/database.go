@@ -1,24 +1,37 @@
 package routes
 
 import (
+	"bufio"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/fuxingjun/go-sqlite-web/app/models"
 	"github.com/fuxingjun/go-sqlite-web/app/services"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
 	"github.com/go-playground/validator/v10"
 	"github.com/gofiber/fiber/v2"
 )
 
 // QueryRequest 查询请求
 type QueryRequest struct {
-	SQL  string `json:"sql"`
-	Page int    `json:"page,omitempty"`
-	Size int    `json:"size,omitempty"`
+	SQL    string `json:"sql"`
+	Params []any  `json:"params,omitempty"` // 绑定参数, 对应 SQL 中的 ?
+	Page   int    `json:"page,omitempty"`
+	Size   int    `json:"size,omitempty"`
+	DryRun bool   `json:"dryRun,omitempty"` // 在保存点中执行后回滚, 返回影响行数和结构差异
 }
 
 var validate = validator.New()
 
+// requestUser 从请求头中获取当前用户, 用于记录查询历史
+func requestUser(c *fiber.Ctx) string {
+	if user := c.Get("X-User"); user != "" {
+		return user
+	}
+	return c.IP()
+}
+
 func DatabaseRoute(router fiber.Router) {
 	// 分组前缀
 	group := router.Group("/db")
@@ -46,6 +59,64 @@ func DatabaseRoute(router fiber.Router) {
 		}
 		return c.JSON(models.OK(views, fmt.Sprintf("%d views found", len(*views))))
 	})
+	// 创建视图
+	group.Post("/view", func(c *fiber.Ctx) error {
+		var req struct {
+			models.CreateViewRequest
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid JSON: " + err.Error()))
+		}
+		if err := validate.Struct(&req.CreateViewRequest); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			stmt, err := services.BuildCreateViewSQL(&req.CreateViewRequest)
+			return dryRunResponse(c, err, stmt)
+		}
+		if err := services.CreateView(&req.CreateViewRequest); err != nil {
+			return c.JSON(models.Err("failed to create view: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("view '%s' created successfully", req.Name)))
+	})
+	// 修改视图: 在一个事务中删除并按新定义重建, name 与路径不同时同时改名
+	group.Put("/view/:name", func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		var req struct {
+			models.CreateViewRequest
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid JSON: " + err.Error()))
+		}
+		if req.Name == "" {
+			req.Name = name
+		}
+		if err := validate.Struct(&req.CreateViewRequest); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			statements, err := services.UpdateViewSQL(name, &req.CreateViewRequest)
+			return dryRunResponse(c, err, statements...)
+		}
+		if err := services.UpdateView(name, &req.CreateViewRequest); err != nil {
+			return c.JSON(models.Err("failed to update view: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("view '%s' updated successfully", req.Name)))
+	})
+	// 删除视图
+	group.Delete("/view/:name", func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if c.QueryBool("dryRun") {
+			stmt, err := services.DropViewSQL(name)
+			return dryRunResponse(c, err, stmt)
+		}
+		if err := services.DropView(name); err != nil {
+			return c.JSON(models.Err("failed to drop view: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("view '%s' dropped successfully", name)))
+	})
 
 	group.Get("/triggers", func(c *fiber.Ctx) error {
 		triggers, err := services.GetAllTriggers()
@@ -54,6 +125,75 @@ func DatabaseRoute(router fiber.Router) {
 		}
 		return c.JSON(models.OK(triggers, fmt.Sprintf("%d triggers found", len(triggers))))
 	})
+	group.Get("/triggers/:name", func(c *fiber.Ctx) error {
+		trigger, err := services.GetTriggerByName(c.Params("name"))
+		if err != nil {
+			return c.JSON(models.Err("failed to load trigger: " + err.Error()))
+		}
+		return c.JSON(models.OK(trigger, ""))
+	})
+	// 创建触发器: 按 timing/event/columns/when/body 生成, 或直接传入 sql
+	group.Post("/triggers", func(c *fiber.Ctx) error {
+		var req struct {
+			models.CreateTriggerRequest
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid JSON: " + err.Error()))
+		}
+		req.Timing = strings.ToUpper(req.Timing)
+		req.Event = strings.ToUpper(req.Event)
+		if err := validate.Struct(&req.CreateTriggerRequest); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			stmt, _, err := services.BuildCreateTriggerSQL(&req.CreateTriggerRequest)
+			return dryRunResponse(c, err, stmt)
+		}
+		trigger, err := services.CreateTrigger(&req.CreateTriggerRequest)
+		if err != nil {
+			return c.JSON(models.Err("failed to create trigger: " + err.Error()))
+		}
+		return c.JSON(models.OK(trigger, fmt.Sprintf("trigger '%s' created successfully", trigger.Name)))
+	})
+	group.Delete("/triggers/:name", func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if c.QueryBool("dryRun") {
+			stmt, err := services.DropTriggerSQL(name)
+			return dryRunResponse(c, err, stmt)
+		}
+		if err := services.DropTrigger(name); err != nil {
+			return c.JSON(models.Err("failed to drop trigger: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("trigger '%s' dropped successfully", name)))
+	})
+	// 停用触发器: 定义保存到附属存储, 之后可重新启用
+	group.Post("/triggers/:name/disable", func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if err := services.DisableTrigger(name); err != nil {
+			return c.JSON(models.Err("failed to disable trigger: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("trigger '%s' disabled", name)))
+	})
+	group.Post("/triggers/:name/enable", func(c *fiber.Ctx) error {
+		name := c.Params("name")
+		if err := services.EnableTrigger(name); err != nil {
+			return c.JSON(models.Err("failed to enable trigger: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("trigger '%s' enabled", name)))
+	})
+	// 外键检查, 列出找不到父行的数据
+	group.Get("/foreign-key-check", func(c *fiber.Ctx) error {
+		limit := c.QueryInt("limit", 1000)
+		if limit <= 0 {
+			limit = 1000
+		}
+		result, err := services.ForeignKeyCheck(c.Query("table"), limit)
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(result, fmt.Sprintf("%d foreign key violations found", result.Total)))
+	})
 	// 创建表
 	group.Post("/table", func(c *fiber.Ctx) error {
 		var req models.CreateTableRequest
@@ -65,6 +205,10 @@ func DatabaseRoute(router fiber.Router) {
 		if err := validate.Struct(&req); err != nil {
 			return c.JSON(models.Err("validation error: " + err.Error()))
 		}
+		if c.QueryBool("dryRun") {
+			stmt, err := services.CreateTableSQL(&req)
+			return dryRunResponse(c, err, stmt)
+		}
 		// 创建表
 		if err := services.CreateSQLiteTable(&req); err != nil {
 			return c.JSON(models.Err("failed to create table: " + err.Error()))
@@ -74,21 +218,312 @@ func DatabaseRoute(router fiber.Router) {
 	// 删除表
 	group.Delete("/table/:tableName", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
+		if c.QueryBool("dryRun") {
+			statements, err := services.DropTableSQL(tableName)
+			return dryRunResponse(c, err, statements...)
+		}
 		if err := services.DropSQLiteTable(tableName); err != nil {
 			return c.JSON(models.Err("failed to drop table: " + err.Error()))
 		}
 		return c.JSON(models.OK(nil, fmt.Sprintf("drop table '%s' successfully", tableName)))
 	})
+	// 删除表前预览: 外键级联影响、随表删除的对象和失效的视图、触发器
+	group.Get("/table/:tableName/drop-preview", func(c *fiber.Ctx) error {
+		impact, err := services.PreviewDropTable(c.Params("tableName"))
+		if err != nil {
+			return c.JSON(models.Err("failed to preview drop: " + err.Error()))
+		}
+		return c.JSON(models.OK(impact, "drop preview generated"))
+	})
+	// 重命名表, 视图、触发器和外键中的引用随之更新
+	group.Put("/table/:tableName", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		var req struct {
+			NewName string `json:"newName" validate:"required"`
+			DryRun  bool   `json:"dryRun"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid JSON: " + err.Error()))
+		}
+		if err := validate.Struct(&req); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			statements, err := services.RenameTableSQL(tableName, req.NewName)
+			return dryRunResponse(c, err, statements...)
+		}
+		if err := services.RenameTable(tableName, req.NewName); err != nil {
+			return c.JSON(models.Err("failed to rename table: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, fmt.Sprintf("table '%s' renamed to '%s'", tableName, req.NewName)))
+	})
+	// 复制表结构, 可选复制数据
+	group.Post("/table/:tableName/copy", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		var req struct {
+			Name     string `json:"name" validate:"required"`
+			WithData bool   `json:"withData"`
+			DryRun   bool   `json:"dryRun"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid JSON: " + err.Error()))
+		}
+		if err := validate.Struct(&req); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			statements, err := services.CopyTableSQL(tableName, req.Name, req.WithData)
+			return dryRunResponse(c, err, statements...)
+		}
+		result, err := services.CopyTable(tableName, req.Name, req.WithData)
+		if err != nil {
+			return c.JSON(models.Err("failed to copy table: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, fmt.Sprintf("table '%s' copied to '%s'", tableName, req.Name)))
+	})
+	// 清空表, 可选重置自增计数
+	group.Post("/table/:tableName/truncate", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		var req struct {
+			ResetSequence bool `json:"resetSequence"`
+			DryRun        bool `json:"dryRun"`
+		}
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&req); err != nil {
+				return c.JSON(models.Err("invalid JSON: " + err.Error()))
+			}
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			statements, err := services.TruncateTableSQL(tableName, req.ResetSequence)
+			return dryRunResponse(c, err, statements...)
+		}
+		result, err := services.TruncateTable(tableName, req.ResetSequence)
+		if err != nil {
+			return c.JSON(models.Err("failed to truncate table: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, fmt.Sprintf("%d rows deleted from '%s'", result.Rows, tableName)))
+	})
 
 	group.Post("/query", func(c *fiber.Ctx) error {
 		var req QueryRequest
 		if err := c.BodyParser(&req); err != nil {
 			return c.JSON(models.Err("invalid request"))
 		}
-		result := services.ExecuteSQL(req.SQL, req.Page, req.Size)
+		// X-Tx-Id 指定时在交互式事务中执行
+		txID := c.Get("X-Tx-Id")
+		if txID != "" {
+			if _, err := services.GetTx(txID); err != nil {
+				return c.JSON(models.Err(err.Error()))
+			}
+		}
+		if req.DryRun {
+			if c.Query("stream") != "" {
+				return c.JSON(models.Err("dryRun cannot be combined with stream"))
+			}
+			result, err := services.DryRunSQL(txID, req.SQL, req.Page, req.Size, req.Params...)
+			if err != nil {
+				return c.JSON(models.Err("dry run failed: " + err.Error()))
+			}
+			return c.JSON(models.OK(result, "dry run completed, changes rolled back"))
+		}
+		// 流式模式: ?stream=ndjson 或 ?stream=sse, 边扫描边推送
+		if stream := c.Query("stream"); stream != "" {
+			if stream != "ndjson" && stream != "sse" {
+				return c.JSON(models.Err("unsupported stream format: " + stream))
+			}
+			contentType := "application/x-ndjson; charset=utf-8"
+			if stream == "sse" {
+				contentType = "text/event-stream; charset=utf-8"
+				c.Set("Cache-Control", "no-cache")
+			}
+			c.Set("Content-Type", contentType)
+			user := requestUser(c)
+			c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+				var event *services.StreamEvent
+				var err error
+				if txID != "" {
+					event, err = services.StreamSelectInTx(txID, req.SQL, req.Page, req.Size, stream, w, req.Params...)
+				} else {
+					event, err = services.StreamSelect(req.SQL, req.Page, req.Size, stream, w, req.Params...)
+				}
+				if err != nil {
+					utils.GetLogger("").Debug("stream query aborted", "error", err)
+				}
+				if event != nil {
+					services.RecordHistory(&services.HistoryEntry{
+						SQL:      req.SQL,
+						Params:   req.Params,
+						User:     user,
+						Duration: event.Duration,
+						RowCount: int64(event.Count),
+						Error:    event.Error,
+					})
+				}
+			})
+			return nil
+		}
+		var result *services.SQLResult
+		if txID != "" {
+			var err error
+			if result, err = services.ExecuteSQLInTx(txID, req.SQL, req.Page, req.Size, req.Params...); err != nil {
+				return c.JSON(models.Err(err.Error()))
+			}
+		} else {
+			result = services.ExecuteSQL(req.SQL, req.Page, req.Size, req.Params...)
+		}
+		rowCount := result.Affected
+		if result.Type == "query" {
+			rowCount = int64(len(result.Rows))
+		}
+		services.RecordHistory(&services.HistoryEntry{
+			SQL:      req.SQL,
+			Params:   req.Params,
+			User:     requestUser(c),
+			Duration: result.Duration,
+			RowCount: rowCount,
+			Error:    result.Error,
+		})
 		return c.JSON(models.OK(result, "query executed"))
 	})
 
+	// 交互式事务: 开启后通过 X-Tx-Id 请求头在 /db/query 中使用
+	group.Post("/tx", func(c *fiber.Ctx) error {
+		info, err := services.BeginTx()
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(info, "transaction started"))
+	})
+
+	group.Get("/tx", func(c *fiber.Ctx) error {
+		list := services.ListTx()
+		return c.JSON(models.OK(list, fmt.Sprintf("%d transactions open", len(list))))
+	})
+
+	group.Get("/tx/:id", func(c *fiber.Ctx) error {
+		info, err := services.GetTx(c.Params("id"))
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(info, "transaction found"))
+	})
+
+	group.Post("/tx/:id/commit", func(c *fiber.Ctx) error {
+		if err := services.CommitTx(c.Params("id")); err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(nil, "transaction committed"))
+	})
+
+	group.Post("/tx/:id/rollback", func(c *fiber.Ctx) error {
+		if err := services.RollbackTx(c.Params("id")); err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(nil, "transaction rolled back"))
+	})
+
+	// 查询历史
+	group.Get("/history", func(c *fiber.Ctx) error {
+		page, _ := strconv.Atoi(c.Query("page", "1"))
+		size, _ := strconv.Atoi(c.Query("size", "50"))
+		history, err := services.GetHistory(services.HistoryQuery{
+			Keyword: c.Query("q"),
+			User:    c.Query("user"),
+			DB:      c.Query("db"),
+			Page:    page,
+			Size:    size,
+		})
+		if err != nil {
+			return c.JSON(models.Err("failed to load history: " + err.Error()))
+		}
+		return c.JSON(models.OK(history, fmt.Sprintf("%d history entries found", history.Total)))
+	})
+
+	// 自动补全元数据
+	group.Get("/completions", func(c *fiber.Ctx) error {
+		completions, err := services.GetCompletions()
+		if err != nil {
+			return c.JSON(models.Err("failed to load completions: " + err.Error()))
+		}
+		return c.JSON(models.OK(completions, "completions retrieved successfully"))
+	})
+
+	// 上下文补全
+	group.Post("/complete", func(c *fiber.Ctx) error {
+		var req struct {
+			SQL    string `json:"sql"`
+			Cursor int    `json:"cursor"` // 光标位置(字节偏移)
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid request"))
+		}
+		result, err := services.Complete(req.SQL, req.Cursor)
+		if err != nil {
+			return c.JSON(models.Err("failed to complete: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, fmt.Sprintf("%d suggestions", len(result.Suggestions))))
+	})
+
+	// SQL 格式化
+	group.Post("/format", func(c *fiber.Ctx) error {
+		var req struct {
+			SQL string `json:"sql"`
+			services.FormatOptions
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid request"))
+		}
+		return c.JSON(models.OK(map[string]any{
+			"sql": services.FormatSQL(req.SQL, req.FormatOptions),
+		}, "sql formatted"))
+	})
+
+	// SQL 检查
+	group.Post("/lint", func(c *fiber.Ctx) error {
+		var req QueryRequest
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid request"))
+		}
+		issues, err := services.LintSQL(req.SQL)
+		if err != nil {
+			return c.JSON(models.Err("failed to lint sql: " + err.Error()))
+		}
+		return c.JSON(models.OK(issues, fmt.Sprintf("%d issues found", len(issues))))
+	})
+
+	// 查询计划分析
+	group.Post("/explain", func(c *fiber.Ctx) error {
+		var req struct {
+			SQL      string `json:"sql"`
+			Bytecode bool   `json:"bytecode,omitempty"` // 是否返回 EXPLAIN 字节码
+			Run      bool   `json:"run,omitempty"`      // 是否实际执行一次以统计耗时
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid request"))
+		}
+		result, err := services.ExplainSQL(req.SQL, req.Bytecode, req.Run)
+		if err != nil {
+			return c.JSON(models.Err("failed to explain query: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, "query plan retrieved successfully"))
+	})
+
+	// 索引建议, 采纳时将 suggestion.index 提交到 POST /table/:tableName/indexes
+	group.Post("/index-advice", func(c *fiber.Ctx) error {
+		var req struct {
+			SQL  string `json:"sql"`
+			Test bool   `json:"test,omitempty"` // 是否在回滚事务中测试候选索引
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.JSON(models.Err("invalid request"))
+		}
+		advice, err := services.AdviseIndexes(req.SQL, req.Test)
+		if err != nil {
+			return c.JSON(models.Err("failed to advise indexes: " + err.Error()))
+		}
+		return c.JSON(models.OK(advice, fmt.Sprintf("%d index suggestions", len(advice.Suggestions))))
+	})
+
 	group.Post("/export", func(c *fiber.Ctx) error {
 		var req QueryRequest
 		if err := c.BodyParser(&req); err != nil {
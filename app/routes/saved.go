@@ -0,0 +1,180 @@
+package routes
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SavedRunRequest 执行保存的查询时可覆盖的参数
+type SavedRunRequest struct {
+	Params []any `json:"params,omitempty"` // 为空时使用保存的默认参数
+	Page   int   `json:"page,omitempty"`
+	Size   int   `json:"size,omitempty"`
+}
+
+func SavedQueryRoute(router fiber.Router) {
+	// 分组前缀
+	group := router.Group("/db/saved")
+
+	// 查询列表
+	group.Get("/", func(c *fiber.Ctx) error {
+		queries, err := services.ListSavedQueries(c.Query("q"), c.Query("tag"))
+		if err != nil {
+			return c.JSON(models.Err("failed to load saved queries: " + err.Error()))
+		}
+		return c.JSON(models.OK(queries, fmt.Sprintf("%d saved queries found", len(queries))))
+	})
+
+	// 导出为 JSON 文件
+	group.Get("/export", func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "application/json; charset=utf-8")
+		c.Set("Content-Disposition", `attachment; filename="saved_queries.json"`)
+		return services.ExportSavedQueries(c.Context().Response.BodyWriter())
+	})
+
+	// 从 JSON 文件导入, 按标题覆盖
+	group.Post("/import", func(c *fiber.Ctx) error {
+		file, err := c.FormFile("file")
+		if err != nil {
+			return c.Status(400).JSON(models.Err("file is required"))
+		}
+		fileReader, err := file.Open()
+		if err != nil {
+			return c.Status(500).JSON(models.Err("failed to read file: " + err.Error()))
+		}
+		defer fileReader.Close()
+		result, err := services.ImportSavedQueries(fileReader)
+		if err != nil {
+			return c.JSON(models.Err("failed to import saved queries: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, fmt.Sprintf("%d created, %d updated", result.Created, result.Updated)))
+	})
+
+	group.Get("/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(models.Err("invalid id"))
+		}
+		query, err := services.GetSavedQuery(id)
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(query, "saved query retrieved successfully"))
+	})
+
+	group.Post("/", func(c *fiber.Ctx) error {
+		var req services.SavedQuery
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		if err := validate.Struct(&req); err != nil {
+			return c.Status(400).JSON(models.Err("validation error: " + err.Error()))
+		}
+		query, err := services.CreateSavedQuery(&req)
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.Status(201).JSON(models.OK(query, "saved query created successfully"))
+	})
+
+	group.Put("/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(models.Err("invalid id"))
+		}
+		var req services.SavedQuery
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		if err := validate.Struct(&req); err != nil {
+			return c.Status(400).JSON(models.Err("validation error: " + err.Error()))
+		}
+		query, err := services.UpdateSavedQuery(id, &req)
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(query, "saved query updated successfully"))
+	})
+
+	group.Delete("/:id", func(c *fiber.Ctx) error {
+		id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(models.Err("invalid id"))
+		}
+		if err := services.DeleteSavedQuery(id); err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		return c.JSON(models.OK(nil, "saved query deleted successfully"))
+	})
+
+	// 执行保存的查询
+	group.Post("/:id/run", func(c *fiber.Ctx) error {
+		query, req, err := loadSavedRun(c)
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		result := services.ExecuteSQL(query.SQL, req.Page, req.Size, req.Params...)
+		rowCount := result.Affected
+		if result.Type == "query" {
+			rowCount = int64(len(result.Rows))
+		}
+		services.RecordHistory(&services.HistoryEntry{
+			SQL:      query.SQL,
+			Params:   req.Params,
+			User:     requestUser(c),
+			Duration: result.Duration,
+			RowCount: rowCount,
+			Error:    result.Error,
+		})
+		return c.JSON(models.OK(result, "query executed"))
+	})
+
+	// 导出保存的查询结果
+	group.Post("/:id/export", func(c *fiber.Ctx) error {
+		query, req, err := loadSavedRun(c)
+		if err != nil {
+			return c.JSON(models.Err(err.Error()))
+		}
+		if req.Page <= 0 {
+			req.Page = 1
+		}
+		if req.Size <= 0 {
+			req.Size = 100000
+		}
+		fileType := c.Query("type", "json")
+		filename, contentType := "data.json", "application/json; charset=utf-8"
+		if fileType == "csv" {
+			filename, contentType = "data.csv", "text/csv; charset=utf-8"
+		}
+		c.Set("Content-Type", contentType)
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+		bw := c.Context().Response.BodyWriter()
+		return services.ExportQuery(query.SQL, req.Page, req.Size, fileType, bw, req.Params...)
+	})
+}
+
+// loadSavedRun 读取保存的查询和请求体中的覆盖参数
+func loadSavedRun(c *fiber.Ctx) (*services.SavedQuery, *SavedRunRequest, error) {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid id")
+	}
+	query, err := services.GetSavedQuery(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	req := new(SavedRunRequest)
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return nil, nil, fmt.Errorf("invalid JSON body: %w", err)
+		}
+	}
+	if len(req.Params) == 0 {
+		req.Params = query.Params
+	}
+	return query, req, nil
+}
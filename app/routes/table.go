@@ -1,6 +1,7 @@
 package routes
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
@@ -11,6 +12,27 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// rowErr 生成写入行失败的响应, 字段转换错误放在 data 中
+func rowErr(prefix string, err error) *models.Response {
+	var fieldErrs services.FieldErrors
+	if errors.As(err, &fieldErrs) {
+		return models.ErrWithData(prefix+err.Error(), fieldErrs)
+	}
+	return models.Err(prefix + err.Error())
+}
+
+// dryRunResponse 试运行 schema 修改语句并返回影响行数、SQL 和结构差异
+func dryRunResponse(c *fiber.Ctx, err error, statements ...string) error {
+	if err != nil {
+		return c.JSON(models.Err("dry run failed: " + err.Error()))
+	}
+	result, err := services.DryRunStatements(statements...)
+	if err != nil {
+		return c.JSON(models.Err("dry run failed: " + err.Error()))
+	}
+	return c.JSON(models.OK(result, "dry run completed, changes rolled back"))
+}
+
 func TableRoute(router fiber.Router) {
 	// 分组前缀
 	group := router.Group("/table")
@@ -41,16 +63,33 @@ func TableRoute(router fiber.Router) {
 		return c.JSON(models.OK(indexes, "table columns retrieved successfully"))
 	})
 
+	// 由表结构生成的 JSON Schema, 新增和修改行时按它校验
+	group.Get("/:tableName/json-schema", func(c *fiber.Ctx) error {
+		schema, err := services.GetTableJSONSchema(c.Params("tableName"))
+		if err != nil {
+			return c.JSON(models.Err("failed to generate JSON schema: " + err.Error()))
+		}
+		return c.JSON(models.OK(schema, "JSON schema generated"))
+	})
+
 	// 新建表字段
 	group.Post("/:tableName/columns", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
-		var column services.NewTableColumnSchema
-		if err := c.BodyParser(&column); err != nil {
+		var body struct {
+			services.NewTableColumnSchema
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&body); err != nil {
 			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
 		}
-		if column.Name == "" || column.Type == "" {
+		column := body.NewTableColumnSchema
+		if column.Name == "" || column.Type == "" && column.Expression == "" {
 			return c.Status(400).JSON(models.Err("column name and type are required"))
 		}
+		if body.DryRun || c.QueryBool("dryRun") {
+			stmt, err := services.NewTableColumnSQL(tableName, column)
+			return dryRunResponse(c, err, stmt)
+		}
 		err := services.NewTableColumn(tableName, column)
 		if err != nil {
 			return c.JSON(models.Err("failed to add column: " + err.Error()))
@@ -62,6 +101,10 @@ func TableRoute(router fiber.Router) {
 	group.Delete("/:tableName/columns/:columnName", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
 		columnName := c.Params("columnName")
+		if c.QueryBool("dryRun") {
+			stmt, err := services.DeleteTableColumnSQL(tableName, columnName)
+			return dryRunResponse(c, err, stmt)
+		}
 		if err := services.DeleteTableColumn(tableName, columnName); err != nil {
 			return c.JSON(models.Err("failed to delete column: " + err.Error()))
 		}
@@ -87,6 +130,113 @@ func TableRoute(router fiber.Router) {
 		return c.JSON(models.OK(nil, "column renamed successfully"))
 	})
 
+	// 按目标定义重建表, 可修改列类型、约束和默认值, dryRun 时只预览 SQL 和结构差异
+	group.Put("/:tableName/schema", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		var body struct {
+			models.CreateTableRequest
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		body.TableName = tableName
+		if err := validate.Struct(&body.CreateTableRequest); err != nil {
+			return c.Status(400).JSON(models.Err("validation error: " + err.Error()))
+		}
+		dryRun := body.DryRun || c.QueryBool("dryRun")
+		result, err := services.RebuildTable(tableName, &body.CreateTableRequest, dryRun)
+		if err != nil {
+			return c.JSON(models.Err("failed to rebuild table: " + err.Error()))
+		}
+		if dryRun {
+			return c.JSON(models.OK(result, "dry run completed, changes rolled back"))
+		}
+		return c.JSON(models.OK(result, "table rebuilt successfully"))
+	})
+
+	// 移动列到新位置(从 0 开始), 通过重建表实现
+	group.Put("/:tableName/columns/:columnName/position", func(c *fiber.Ctx) error {
+		var body struct {
+			Position *int `json:"position"`
+			DryRun   bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		if body.Position == nil {
+			return c.Status(400).JSON(models.Err("position is required"))
+		}
+		dryRun := body.DryRun || c.QueryBool("dryRun")
+		result, err := services.MoveColumn(c.Params("tableName"), c.Params("columnName"), *body.Position, dryRun)
+		if err != nil {
+			return c.JSON(models.Err("failed to move column: " + err.Error()))
+		}
+		if dryRun {
+			return c.JSON(models.OK(result, "dry run completed, changes rolled back"))
+		}
+		return c.JSON(models.OK(result, "column moved successfully"))
+	})
+
+	// 预览修改列类型时无法干净转换的值
+	group.Get("/:tableName/columns/:columnName/type", func(c *fiber.Ctx) error {
+		newType := c.Query("type")
+		if newType == "" {
+			return c.Status(400).JSON(models.Err("type is required"))
+		}
+		preview, err := services.PreviewColumnType(c.Params("tableName"), c.Params("columnName"), newType)
+		if err != nil {
+			return c.JSON(models.Err("failed to preview conversion: " + err.Error()))
+		}
+		return c.JSON(models.OK(preview, fmt.Sprintf("%d of %d values would not convert cleanly", preview.Failed, preview.Total)))
+	})
+
+	// 修改列类型, 存在无法转换的值时需要 force
+	group.Put("/:tableName/columns/:columnName/type", func(c *fiber.Ctx) error {
+		var body struct {
+			Type   string `json:"type"`
+			DryRun bool   `json:"dryRun"`
+			Force  bool   `json:"force"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		if body.Type == "" {
+			return c.Status(400).JSON(models.Err("type is required"))
+		}
+		dryRun := body.DryRun || c.QueryBool("dryRun")
+		result, err := services.ChangeColumnType(c.Params("tableName"), c.Params("columnName"), body.Type, dryRun, body.Force)
+		if err != nil {
+			return c.JSON(models.ErrWithData("failed to change column type: "+err.Error(), result))
+		}
+		if dryRun {
+			return c.JSON(models.OK(result, "dry run completed, changes rolled back"))
+		}
+		return c.JSON(models.OK(result, "column type changed successfully"))
+	})
+
+	// 通过重建转换为 STRICT 表, types 可指定列的目标类型
+	group.Post("/:tableName/strict", func(c *fiber.Ctx) error {
+		var body struct {
+			Types  map[string]string `json:"types"`
+			DryRun bool              `json:"dryRun"`
+		}
+		if len(c.Body()) > 0 {
+			if err := c.BodyParser(&body); err != nil {
+				return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+			}
+		}
+		dryRun := body.DryRun || c.QueryBool("dryRun")
+		result, err := services.ConvertToStrict(c.Params("tableName"), body.Types, dryRun)
+		if err != nil {
+			return c.JSON(models.ErrWithData("failed to convert to STRICT: "+err.Error(), result))
+		}
+		if dryRun {
+			return c.JSON(models.OK(result, "dry run completed, changes rolled back"))
+		}
+		return c.JSON(models.OK(result, "table converted to STRICT successfully"))
+	})
+
 	// 查询表索引
 	group.Get("/:tableName/indexes", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
@@ -100,12 +250,20 @@ func TableRoute(router fiber.Router) {
 	// 新建表索引
 	group.Post("/:tableName/indexes", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
-		var index services.NewTableIndexSchema
-		if err := c.BodyParser(&index); err != nil {
+		var body struct {
+			services.NewTableIndexSchema
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&body); err != nil {
 			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
 		}
-		if index.Name == "" || len(index.Columns) == 0 {
-			return c.Status(400).JSON(models.Err("index name and columns are required"))
+		index := body.NewTableIndexSchema
+		if index.Name == "" || len(index.Columns) == 0 && len(index.Items) == 0 {
+			return c.Status(400).JSON(models.Err("index name and columns or items are required"))
+		}
+		if body.DryRun || c.QueryBool("dryRun") {
+			stmt, err := services.NewTableIndexSQL(tableName, index)
+			return dryRunResponse(c, err, stmt)
 		}
 		err := services.NewTableIndex(tableName, index)
 		if err != nil {
@@ -118,12 +276,67 @@ func TableRoute(router fiber.Router) {
 	group.Delete("/:tableName/indexes/:indexName", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
 		indexName := c.Params("indexName")
+		if c.QueryBool("dryRun") {
+			stmt, err := services.DeleteTableIndexSQL(tableName, indexName)
+			return dryRunResponse(c, err, stmt)
+		}
 		if err := services.DeleteTableIndex(tableName, indexName); err != nil {
 			return c.JSON(models.Err("failed to delete index: " + err.Error()))
 		}
 		return c.JSON(models.OK(nil, "index deleted successfully"))
 	})
 
+	// 查询表的外键和指向本表的外键
+	group.Get("/:tableName/foreign-keys", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		foreignKeys, err := services.GetTableForeignKeys(tableName)
+		if err != nil {
+			return c.JSON(models.Err("failed to get foreign keys: " + err.Error()))
+		}
+		referencedBy, err := services.GetReferencingForeignKeys(tableName)
+		if err != nil {
+			return c.JSON(models.Err("failed to get referencing foreign keys: " + err.Error()))
+		}
+		return c.JSON(models.OK(map[string]any{
+			"foreignKeys":  foreignKeys,
+			"referencedBy": referencedBy,
+		}, "foreign keys retrieved successfully"))
+	})
+
+	// 新增外键, 通过重建表完成
+	group.Post("/:tableName/foreign-keys", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		var body struct {
+			models.ForeignKey
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		if err := validate.Struct(&body.ForeignKey); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		result, err := services.AddForeignKey(tableName, &body.ForeignKey, body.DryRun || c.QueryBool("dryRun"))
+		if err != nil {
+			return c.JSON(models.Err("failed to add foreign key: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, "foreign key added successfully"))
+	})
+
+	// 删除外键, id 来自 PRAGMA foreign_key_list
+	group.Delete("/:tableName/foreign-keys/:id", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		id, err := c.ParamsInt("id")
+		if err != nil {
+			return c.Status(400).JSON(models.Err("invalid foreign key id"))
+		}
+		result, err := services.DropForeignKey(tableName, id, c.QueryBool("dryRun"))
+		if err != nil {
+			return c.JSON(models.Err("failed to delete foreign key: " + err.Error()))
+		}
+		return c.JSON(models.OK(result, "foreign key deleted successfully"))
+	})
+
 	// 查询表数据
 	group.Get("/:tableName/rows", func(c *fiber.Ctx) error {
 		tableName := c.Params("tableName")
@@ -137,18 +350,108 @@ func TableRoute(router fiber.Router) {
 		}
 		offset := (page - 1) * limit
 
-		resp, err := services.GetTableData(tableName, limit, offset)
+		// q 非空时用全文索引搜索, 按相关度排序
+		var resp *services.QueryTableResult
+		var err error
+		if q := c.Query("q"); q != "" {
+			resp, err = services.SearchTableData(tableName, q, limit, offset)
+		} else {
+			resp, err = services.GetTableData(tableName, limit, offset)
+		}
 		if err != nil {
 			return c.JSON(models.Err("failed to get table data: " + err.Error()))
 		}
 
-		return c.JSON(models.OK(map[string]any{
+		data := map[string]any{
 			"rows":       resp.Data,
 			"total":      resp.Total,
 			"page":       page,
 			"totalPages": (resp.Total + limit - 1) / limit,
 			"limit":      limit,
-		}, ""))
+		}
+		// 外键单元格指向的父行
+		if resp.Links != nil {
+			data["links"] = resp.Links
+		}
+		// 全文搜索的排名和高亮片段
+		if resp.Matches != nil {
+			data["matches"] = resp.Matches
+		}
+		return c.JSON(models.OK(data, ""))
+	})
+
+	// 全文索引: FTS5 外部内容虚拟表 <表名>_fts, 由触发器与表同步
+	group.Get("/:tableName/fts", func(c *fiber.Ctx) error {
+		index, err := services.GetFTSIndex(c.Params("tableName"))
+		if err != nil {
+			return c.JSON(models.Err("failed to get full-text index: " + err.Error()))
+		}
+		return c.JSON(models.OK(index, ""))
+	})
+	group.Post("/:tableName/fts", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		var req struct {
+			services.NewFTSIndexSchema
+			DryRun bool `json:"dryRun"`
+		}
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(models.Err("invalid JSON body: " + err.Error()))
+		}
+		if err := validate.Struct(&req.NewFTSIndexSchema); err != nil {
+			return c.JSON(models.Err("validation error: " + err.Error()))
+		}
+		if req.DryRun || c.QueryBool("dryRun") {
+			statements, err := services.CreateFTSIndexSQL(tableName, req.NewFTSIndexSchema)
+			return dryRunResponse(c, err, statements...)
+		}
+		index, err := services.CreateFTSIndex(tableName, req.NewFTSIndexSchema)
+		if err != nil {
+			return c.JSON(models.Err("failed to create full-text index: " + err.Error()))
+		}
+		return c.JSON(models.OK(index, "full-text index created successfully"))
+	})
+	group.Post("/:tableName/fts/rebuild", func(c *fiber.Ctx) error {
+		index, err := services.RebuildFTSIndex(c.Params("tableName"))
+		if err != nil {
+			return c.JSON(models.Err("failed to rebuild full-text index: " + err.Error()))
+		}
+		return c.JSON(models.OK(index, "full-text index rebuilt successfully"))
+	})
+	group.Delete("/:tableName/fts", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		if c.QueryBool("dryRun") {
+			statements, err := services.DropFTSIndexSQL(tableName)
+			return dryRunResponse(c, err, statements...)
+		}
+		if err := services.DropFTSIndex(tableName); err != nil {
+			return c.JSON(models.Err("failed to delete full-text index: " + err.Error()))
+		}
+		return c.JSON(models.OK(nil, "full-text index deleted successfully"))
+	})
+
+	// 删除行前预览外键级联、置空和阻止删除的数据, 行由主键查询参数指定
+	group.Get("/:tableName/row/delete-preview", func(c *fiber.Ctx) error {
+		impact, err := services.PreviewDeleteRow(c.Params("tableName"), c.Queries())
+		if err != nil {
+			return c.JSON(models.Err("failed to preview delete: " + err.Error()))
+		}
+		return c.JSON(models.OK(impact, "delete preview generated"))
+	})
+
+	// 引用指定行的子表数据, 行由主键查询参数指定, 如 ?id=1
+	group.Get("/:tableName/row/children", func(c *fiber.Ctx) error {
+		tableName := c.Params("tableName")
+		limit := c.QueryInt("limit", 20)
+		if limit <= 0 {
+			limit = 20
+		}
+		key := c.Queries()
+		delete(key, "limit")
+		children, err := services.GetChildRows(tableName, key, limit)
+		if err != nil {
+			return c.JSON(models.Err("failed to get child rows: " + err.Error()))
+		}
+		return c.JSON(models.OK(children, fmt.Sprintf("%d foreign keys reference this row", len(children))))
 	})
 
 	// 新建数据行
@@ -160,7 +463,7 @@ func TableRoute(router fiber.Router) {
 		}
 		id, err := services.InsertRow(tableName, data)
 		if err != nil {
-			return c.JSON(models.Err("insert failed: " + err.Error()))
+			return c.JSON(rowErr("insert failed: ", err))
 		}
 		return c.Status(201).JSON(models.OK(map[string]any{
 			"id": id,
@@ -176,7 +479,7 @@ func TableRoute(router fiber.Router) {
 		}
 		res, err := services.UpdateRow(tableName, data)
 		if err != nil {
-			return c.JSON(models.Err("update failed: " + err.Error()))
+			return c.JSON(rowErr("update failed: ", err))
 		}
 		return c.JSON(models.OK(map[string]any{
 			"rowsAffected": res,
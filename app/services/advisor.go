@@ -0,0 +1,407 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// IndexSuggestion 一条索引建议
+// Index 字段与 NewTableIndexSchema 一致, 采纳时直接提交到 POST /table/:tableName/indexes
+type IndexSuggestion struct {
+	Table      string              `json:"table"`
+	Index      NewTableIndexSchema `json:"index"`
+	SQL        string              `json:"sql"`
+	Reason     string              `json:"reason"`
+	PlanBefore []*PlanNode         `json:"planBefore,omitempty"`
+	PlanAfter  []*PlanNode         `json:"planAfter,omitempty"`
+	Improved   *bool               `json:"improved,omitempty"` // 测试后全表扫描是否消除
+}
+
+// IndexAdvice 索引建议结果
+type IndexAdvice struct {
+	SQL         string             `json:"sql"`
+	Plan        []*PlanNode        `json:"plan"`
+	Suggestions []*IndexSuggestion `json:"suggestions"`
+}
+
+// 查询计划中全表扫描的描述, 如 SCAN orders 或 SCAN 订单 AS o, 计划中的名称不加引号
+var scanDetailPattern = regexp.MustCompile(`(?i)^SCAN\s+(?:TABLE\s+)?(.+?)(?:\s+AS\s+(.+?))?(?:\s+USING\s.*)?$`)
+
+// 条件中的比较运算符, 等值类的列排在索引前面
+var predicateOps = map[string]bool{"=": true, "==": true, "<>": true, "!=": true, "<=": true, ">=": true, "<": true, ">": true}
+
+// 不会作为表别名出现的关键字
+var advisorKeywords = map[string]bool{
+	"WHERE": true, "JOIN": true, "LEFT": true, "RIGHT": true, "INNER": true, "OUTER": true,
+	"CROSS": true, "NATURAL": true, "ON": true, "USING": true, "GROUP": true, "ORDER": true,
+	"LIMIT": true, "UNION": true, "EXCEPT": true, "INTERSECT": true, "HAVING": true, "WINDOW": true,
+}
+
+// columnUsage 记录某张表的列在查询中的用途
+type columnUsage struct {
+	equality []string
+	ranges   []string
+	order    []string
+}
+
+// AdviseIndexes 根据查询计划中的全表扫描, 结合 WHERE/JOIN/ORDER BY 中的列给出索引建议
+// test 为 true 时, 在回滚的事务中实际创建候选索引, 对比前后的查询计划
+func AdviseIndexes(sqlStr string, test bool) (*IndexAdvice, error) {
+	explain, err := ExplainSQL(sqlStr, false, false)
+	if err != nil {
+		return nil, err
+	}
+	advice := &IndexAdvice{
+		SQL:         explain.SQL,
+		Plan:        explain.Plan,
+		Suggestions: []*IndexSuggestion{},
+	}
+
+	// 按词法单元解析, 字符串字面量和带引号的标识符(中文、含 - 的名称)都能正确识别
+	sig := significantTokens(tokenizeSQL(explain.SQL))
+	aliases := parseTableAliases(sig)
+
+	// 找出被全表扫描的表
+	var scanned []string
+	walkPlan(explain.Plan, func(n *PlanNode) {
+		if !n.FullScan {
+			return
+		}
+		m := scanDetailPattern.FindStringSubmatch(n.Detail)
+		if m == nil {
+			return
+		}
+		name := m[1]
+		if m[2] != "" {
+			name = m[2]
+		}
+		if table, ok := aliases[strings.ToLower(name)]; ok && !slices.Contains(scanned, table) {
+			scanned = append(scanned, table)
+		}
+	})
+	if len(scanned) == 0 {
+		return advice, nil
+	}
+
+	// 加载涉及表的列信息
+	tableColumns := make(map[string][]string)
+	for _, table := range uniqueTables(aliases) {
+		cols, err := GetTableColumns(table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns of %s: %w", table, err)
+		}
+		for _, col := range cols {
+			tableColumns[table] = append(tableColumns[table], col.Name)
+		}
+	}
+
+	usages := collectColumnUsage(sig, aliases, tableColumns)
+	for _, table := range scanned {
+		usage := usages[table]
+		if usage == nil {
+			continue
+		}
+		columns := orderIndexColumns(usage)
+		if len(columns) == 0 {
+			continue
+		}
+		covered, err := isCoveredByIndex(table, columns)
+		if err != nil {
+			return nil, err
+		}
+		if covered {
+			continue
+		}
+		index := NewTableIndexSchema{
+			Name:    "idx_" + table + "_" + strings.Join(columns, "_"),
+			Columns: columns,
+		}
+		suggestion := &IndexSuggestion{
+			Table:  table,
+			Index:  index,
+			SQL:    fmt.Sprintf(`CREATE INDEX %s ON %s (%s)`, utils.QuoteIdentifier(index.Name), utils.QuoteIdentifier(table), strings.Join(quotedColumns(columns), ", ")),
+			Reason: describeUsage(table, usage),
+		}
+		if test {
+			if err := testIndexSuggestion(explain.SQL, suggestion); err != nil {
+				return nil, err
+			}
+		}
+		advice.Suggestions = append(advice.Suggestions, suggestion)
+	}
+	return advice, nil
+}
+
+// parseTableAliases 解析 FROM/JOIN 中的表及别名, 返回 小写别名/表名 -> 表名
+func parseTableAliases(sig []sqlToken) map[string]string {
+	aliases := make(map[string]string)
+	inFrom := false
+	for i := 0; i < len(sig); i++ {
+		t := sig[i]
+		switch {
+		case t.Is("FROM", "JOIN"):
+			inFrom = true
+		case t.Is("WHERE", "ON", "USING", "GROUP", "ORDER", "HAVING", "LIMIT", "WINDOW") || t.IsOp("(", ")"):
+			inFrom = false
+			continue
+		case !(inFrom && t.IsOp(",")):
+			continue
+		}
+		// 子查询和关键字不是表名, schema.table 取表名
+		j := i + 1
+		if j >= len(sig) || !sig[j].IsIdent() || isKeyword(sig[j]) {
+			continue
+		}
+		if j+2 < len(sig) && sig[j+1].IsOp(".") && sig[j+2].IsIdent() {
+			j += 2
+		}
+		table := sig[j].Ident()
+		aliases[strings.ToLower(table)] = table
+		j++
+		if j < len(sig) && sig[j].Is("AS") {
+			j++
+		}
+		if j < len(sig) && sig[j].IsIdent() && !isKeyword(sig[j]) && !advisorKeywords[sig[j].Upper()] {
+			aliases[strings.ToLower(sig[j].Ident())] = table
+		}
+		i = j - 1
+	}
+	return aliases
+}
+
+func uniqueTables(aliases map[string]string) []string {
+	var tables []string
+	for _, table := range aliases {
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	slices.Sort(tables)
+	return tables
+}
+
+// resolveColumn 根据限定符或列名推断列所属的表
+func resolveColumn(qualifier, column string, aliases map[string]string, tableColumns map[string][]string) (string, string, bool) {
+	if qualifier != "" {
+		table, ok := aliases[strings.ToLower(qualifier)]
+		if !ok {
+			return "", "", false
+		}
+		for _, col := range tableColumns[table] {
+			if strings.EqualFold(col, column) {
+				return table, col, true
+			}
+		}
+		return "", "", false
+	}
+	// 未限定的列, 仅当只有一张表包含该列时才能确定
+	var found, name string
+	for table, cols := range tableColumns {
+		for _, col := range cols {
+			if strings.EqualFold(col, column) {
+				if found != "" {
+					return "", "", false
+				}
+				found, name = table, col
+			}
+		}
+	}
+	return found, name, found != ""
+}
+
+// collectColumnUsage 收集 WHERE/ON 条件和 ORDER BY 中用到的列
+func collectColumnUsage(sig []sqlToken, aliases map[string]string, tableColumns map[string][]string) map[string]*columnUsage {
+	usages := make(map[string]*columnUsage)
+	get := func(table string) *columnUsage {
+		if usages[table] == nil {
+			usages[table] = &columnUsage{}
+		}
+		return usages[table]
+	}
+	add := func(list *[]string, col string) {
+		if !slices.Contains(*list, col) {
+			*list = append(*list, col)
+		}
+	}
+
+	// columnAt 读取以 i 结尾的 [限定符.]列名
+	columnAt := func(i int) (string, string, bool) {
+		if i < 0 || !sig[i].IsIdent() || isKeyword(sig[i]) {
+			return "", "", false
+		}
+		if i >= 2 && sig[i-1].IsOp(".") && sig[i-2].IsIdent() {
+			return sig[i-2].Ident(), sig[i].Ident(), true
+		}
+		return "", sig[i].Ident(), true
+	}
+
+	// 条件只在 FROM 之后出现
+	from := len(sig)
+	for i, t := range sig {
+		if t.Is("FROM") {
+			from = i
+			break
+		}
+	}
+	for i := from + 1; i < len(sig); i++ {
+		t := sig[i]
+		op := ""
+		switch {
+		case t.Kind == tokOp && predicateOps[t.Text]:
+			op = t.Text
+		case t.Is("IN", "LIKE", "GLOB", "IS", "BETWEEN"):
+			op = t.Upper()
+		default:
+			continue
+		}
+		qualifier, column, ok := columnAt(i - 1)
+		if !ok {
+			continue
+		}
+		table, col, ok := resolveColumn(qualifier, column, aliases, tableColumns)
+		if !ok {
+			continue
+		}
+		switch op {
+		case "=", "==", "IN", "IS":
+			add(&get(table).equality, col)
+		default:
+			add(&get(table).ranges, col)
+		}
+	}
+
+	// ORDER BY 的每一项以 [限定符.]列名 开头时计入排序列
+	for i := 0; i+1 < len(sig); i++ {
+		if !sig[i].Is("ORDER") || !sig[i+1].Is("BY") {
+			continue
+		}
+		depth := 0
+		itemStart := i + 2
+		for j := itemStart; j <= len(sig); j++ {
+			end := j == len(sig) || depth == 0 && (sig[j].Is("LIMIT", "OFFSET") || sig[j].IsOp(")", ";"))
+			if end || depth == 0 && sig[j].IsOp(",") {
+				k := itemStart
+				if k+2 < j && sig[k+1].IsOp(".") {
+					k += 2
+				}
+				if k < j && (k+1 == j || !sig[k+1].IsOp("(")) {
+					if qualifier, column, ok := columnAt(k); ok {
+						if table, col, ok := resolveColumn(qualifier, column, aliases, tableColumns); ok {
+							add(&get(table).order, col)
+						}
+					}
+				}
+				itemStart = j + 1
+			}
+			if end {
+				break
+			}
+			if sig[j].IsOp("(") {
+				depth++
+			} else if sig[j].IsOp(")") {
+				depth--
+			}
+		}
+	}
+	return usages
+}
+
+// orderIndexColumns 等值列在前, 范围列其次(最多一个), 排序列最后
+func orderIndexColumns(usage *columnUsage) []string {
+	var columns []string
+	add := func(col string) {
+		if !slices.Contains(columns, col) {
+			columns = append(columns, col)
+		}
+	}
+	for _, col := range usage.equality {
+		add(col)
+	}
+	if len(usage.ranges) > 0 {
+		add(usage.ranges[0])
+	} else {
+		for _, col := range usage.order {
+			add(col)
+		}
+	}
+	return columns
+}
+
+// isCoveredByIndex 判断是否已有索引以这些列为前缀
+func isCoveredByIndex(table string, columns []string) (bool, error) {
+	indexes, err := GetTableIndexes(table)
+	if err != nil {
+		return false, err
+	}
+	for _, index := range indexes {
+		if len(index.Columns) >= len(columns) && slices.Equal(index.Columns[:len(columns)], columns) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func describeUsage(table string, usage *columnUsage) string {
+	var parts []string
+	if len(usage.equality) > 0 {
+		parts = append(parts, "equality on "+strings.Join(usage.equality, ", "))
+	}
+	if len(usage.ranges) > 0 {
+		parts = append(parts, "range on "+strings.Join(usage.ranges, ", "))
+	}
+	if len(usage.order) > 0 {
+		parts = append(parts, "order by "+strings.Join(usage.order, ", "))
+	}
+	return fmt.Sprintf("full scan of %s with %s", table, strings.Join(parts, "; "))
+}
+
+// testIndexSuggestion 在事务中创建候选索引并获取查询计划, 最后回滚
+func testIndexSuggestion(sqlStr string, suggestion *IndexSuggestion) error {
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before []queryPlanRow
+	if err := tx.Select(&before, "EXPLAIN QUERY PLAN "+sqlStr); err != nil {
+		return fmt.Errorf("explain query plan failed: %w", err)
+	}
+	if _, err := tx.Exec(suggestion.SQL); err != nil {
+		return fmt.Errorf("create candidate index failed: %w", err)
+	}
+	var after []queryPlanRow
+	if err := tx.Select(&after, "EXPLAIN QUERY PLAN "+sqlStr); err != nil {
+		return fmt.Errorf("explain query plan failed: %w", err)
+	}
+	suggestion.PlanBefore = buildPlanTree(before)
+	suggestion.PlanAfter = buildPlanTree(after)
+	improved := countFullScans(suggestion.PlanAfter) < countFullScans(suggestion.PlanBefore) ||
+		countTempTrees(suggestion.PlanAfter) < countTempTrees(suggestion.PlanBefore)
+	suggestion.Improved = &improved
+	return nil
+}
+
+func countFullScans(plan []*PlanNode) int {
+	count := 0
+	walkPlan(plan, func(n *PlanNode) {
+		if n.FullScan {
+			count++
+		}
+	})
+	return count
+}
+
+func countTempTrees(plan []*PlanNode) int {
+	count := 0
+	walkPlan(plan, func(n *PlanNode) {
+		if n.TempTree {
+			count++
+		}
+	})
+	return count
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// 合法的声明类型: 若干单词, 可带 (n) 或 (n, m)
+var columnTypePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*( +[A-Za-z_][A-Za-z0-9_]*)*( *\( *[+-]?\d+ *(, *[+-]?\d+ *)?\))?$`)
+
+// ConversionIssue 一个无法干净转换的值
+type ConversionIssue struct {
+	RowID    any    `json:"rowid,omitempty"` // WITHOUT ROWID 表没有 rowid
+	Value    any    `json:"value"`
+	StoredAs string `json:"storedAs"` // 当前的存储类型
+	Result   string `json:"result"`   // 按新类型亲和性转换后的存储类型
+}
+
+// ConversionPreview 修改列类型前的数据转换预览
+type ConversionPreview struct {
+	Column   string             `json:"column"`
+	FromType string             `json:"fromType"`
+	ToType   string             `json:"toType"`
+	Affinity string             `json:"affinity"` // 新类型的亲和性
+	Strict   bool               `json:"strict"`   // STRICT 表中转换失败的值会导致修改失败
+	Total    int64              `json:"total"`    // 非 NULL 值数量
+	Failed   int64              `json:"failed"`   // 无法干净转换的数量
+	Samples  []*ConversionIssue `json:"samples"`  // 部分无法转换的值
+}
+
+// ColumnTypeResult 修改列类型的结果
+type ColumnTypeResult struct {
+	Conversion *ConversionPreview `json:"conversion"`
+	Rebuild    *RebuildResult     `json:"rebuild,omitempty"`
+}
+
+// columnAffinity 按 SQLite 规则根据声明类型确定列亲和性
+func columnAffinity(typ string) string {
+	upper := strings.ToUpper(typ)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return "INTEGER"
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return "TEXT"
+	case upper == "", upper == "ANY", strings.Contains(upper, "BLOB"):
+		return "BLOB"
+	case strings.Contains(upper, "REAL"), strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"):
+		return "REAL"
+	default:
+		return "NUMERIC"
+	}
+}
+
+// 各亲和性下视为转换成功的存储类型, BLOB 亲和性不做转换
+var affinityStorageTypes = map[string]string{
+	"INTEGER": "'integer'",
+	"REAL":    "'real'",
+	"NUMERIC": "'integer', 'real'",
+	"TEXT":    "'text'",
+}
+
+// loadTableDDL 读取并解析表的 CREATE TABLE 语句
+func loadTableDDL(db sqlx.Queryer, table string) (*createTableDDL, error) {
+	var ddlSQL string
+	if err := sqlx.Get(db, &ddlSQL, "SELECT sql FROM sqlite_master WHERE type='table' AND name=?", table); err != nil {
+		return nil, fmt.Errorf("table not found: %s", table)
+	}
+	return parseCreateTable(ddlSQL)
+}
+
+// isStrictTable 判断表选项中是否包含 STRICT
+func isStrictTable(ddl *createTableDDL) bool {
+	for _, t := range significantTokens(tokenizeSQL(ddl.Options)) {
+		if t.Is("STRICT") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateColumnType 校验新的声明类型
+func validateColumnType(ddl *createTableDDL, typ string) error {
+	if !columnTypePattern.MatchString(typ) {
+		return fmt.Errorf("invalid column type: %s", typ)
+	}
+	if isStrictTable(ddl) && !strictTypes[strings.ToUpper(typ)] {
+		return fmt.Errorf("type %s is not allowed in a STRICT table", typ)
+	}
+	return nil
+}
+
+// PreviewColumnType 预览将列改为新类型时无法干净转换的值
+func PreviewColumnType(table, column, newType string) (*ConversionPreview, error) {
+	if !IsValidIdentifier(table) || !IsValidIdentifier(column) {
+		return nil, fmt.Errorf("invalid table or column name")
+	}
+	ddl, err := loadTableDDL(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	col := ddl.Column(column)
+	if col == nil {
+		return nil, fmt.Errorf("no such column: %s.%s", table, column)
+	}
+	if err := validateColumnType(ddl, newType); err != nil {
+		return nil, err
+	}
+	return previewConversion(table, col, newType, isStrictTable(ddl))
+}
+
+// previewConversion 把列的值写入一个新类型的临时表, 根据转换后的存储类型找出不能干净转换的值
+func previewConversion(table string, col *ddlItem, newType string, strict bool) (*ConversionPreview, error) {
+	preview := &ConversionPreview{
+		Column:   col.Name,
+		FromType: col.Type,
+		ToType:   newType,
+		Affinity: columnAffinity(newType),
+		Strict:   strict,
+		Samples:  []*ConversionIssue{},
+	}
+
+	// 临时表只在该事务中存在, 回滚后自动删除
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rowID := "rowid"
+	if rows, err := tx.Queryx("SELECT rowid FROM " + utils.QuoteIdentifier(table) + " LIMIT 0"); err != nil {
+		rowID = "NULL"
+	} else {
+		rows.Close()
+	}
+	if _, err := tx.Exec("CREATE TEMP TABLE _conversion_preview (k, v_old, v_new " + newType + ")"); err != nil {
+		return nil, fmt.Errorf("failed to create preview table: %w", err)
+	}
+	quotedCol := utils.QuoteIdentifier(col.Name)
+	_, err = tx.Exec(fmt.Sprintf("INSERT INTO temp._conversion_preview SELECT %s, %s, %s FROM %s WHERE %s IS NOT NULL",
+		rowID, quotedCol, quotedCol, utils.QuoteIdentifier(table), quotedCol))
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy values: %w", err)
+	}
+	if err := tx.Get(&preview.Total, "SELECT count(*) FROM temp._conversion_preview"); err != nil {
+		return nil, fmt.Errorf("failed to count values: %w", err)
+	}
+
+	storageTypes, ok := affinityStorageTypes[preview.Affinity]
+	if !ok {
+		return preview, nil
+	}
+	where := "typeof(v_new) NOT IN (" + storageTypes + ")"
+	if err := tx.Get(&preview.Failed, "SELECT count(*) FROM temp._conversion_preview WHERE "+where); err != nil {
+		return nil, fmt.Errorf("failed to count failed values: %w", err)
+	}
+	rows, err := tx.Queryx("SELECT k, v_old, typeof(v_old), typeof(v_new) FROM temp._conversion_preview WHERE " + where + " LIMIT 20")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load failed values: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		issue := &ConversionIssue{}
+		if err := rows.Scan(&issue.RowID, &issue.Value, &issue.StoredAs, &issue.Result); err != nil {
+			return nil, fmt.Errorf("failed to scan value: %w", err)
+		}
+		if b, ok := issue.Value.([]byte); ok && issue.StoredAs == "text" {
+			issue.Value = string(b)
+		}
+		preview.Samples = append(preview.Samples, issue)
+	}
+	return preview, rows.Err()
+}
+
+// ddlRebuildTarget 以修改后的原始建表语句作为重建目标, 保留原有的约束写法
+func ddlRebuildTarget(ddl *createTableDDL) rebuildTarget {
+	var columns []string
+	for _, col := range ddl.Columns() {
+		columns = append(columns, col.Name)
+	}
+	return rebuildTarget{
+		createSQL: func(name string) (string, error) {
+			return ddl.Render(utils.QuoteIdentifier(name)), nil
+		},
+		columns: columns,
+	}
+}
+
+// ChangeColumnType 修改列的声明类型, 存在无法干净转换的值时需要 force
+func ChangeColumnType(table, column, newType string, dryRun, force bool) (*ColumnTypeResult, error) {
+	newType = strings.TrimSpace(newType)
+	preview, err := PreviewColumnType(table, column, newType)
+	if err != nil {
+		return nil, err
+	}
+	result := &ColumnTypeResult{Conversion: preview}
+	if preview.Failed > 0 && !dryRun && !force {
+		return result, fmt.Errorf("%d values would not convert cleanly to %s, pass force to continue", preview.Failed, newType)
+	}
+
+	ddl, err := loadTableDDL(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	ddl.Column(column).SetType(newType)
+	result.Rebuild, err = rebuildTable(table, ddlRebuildTarget(ddl), dryRun)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// MoveColumn 将列移动到新位置(从 0 开始), 索引和触发器按列名引用, 重建后保持不变
+func MoveColumn(table, column string, position int, dryRun bool) (*RebuildResult, error) {
+	if !IsValidIdentifier(table) || !IsValidIdentifier(column) {
+		return nil, fmt.Errorf("invalid table or column name")
+	}
+	ddl, err := loadTableDDL(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	columns := ddl.Columns()
+	from := -1
+	for i, col := range columns {
+		if strings.EqualFold(col.Name, column) {
+			from = i
+		}
+	}
+	if from < 0 {
+		return nil, fmt.Errorf("no such column: %s.%s", table, column)
+	}
+	if position < 0 || position >= len(columns) {
+		return nil, fmt.Errorf("position out of range: %d (table has %d columns)", position, len(columns))
+	}
+
+	moved := columns[from]
+	columns = append(columns[:from], columns[from+1:]...)
+	columns = append(columns[:position], append([]*ddlItem{moved}, columns[position:]...)...)
+	// 列定义在前, 表级约束保持原顺序
+	items := columns
+	for _, item := range ddl.Items {
+		if item.Constraint {
+			items = append(items, item)
+		}
+	}
+	ddl.Items = items
+	return rebuildTable(table, ddlRebuildTarget(ddl), dryRun)
+}
+
+// StrictConversionResult 转换为 STRICT 表的预览和结果
+type StrictConversionResult struct {
+	Columns []*ConversionPreview `json:"columns"` // 每列的目标类型和无法存入的值
+	Failed  int64                `json:"failed"`  // 无法存入 STRICT 列的值总数
+	Rebuild *RebuildResult       `json:"rebuild,omitempty"`
+}
+
+// strictType 返回列在 STRICT 表中的类型: 已是允许的类型时保持不变, 布尔用 INTEGER, 其余按亲和性选择, NUMERIC 亲和性用 ANY 保留原值
+func strictType(typ string) string {
+	if upper := strings.ToUpper(strings.TrimSpace(typ)); strictTypes[upper] {
+		return upper
+	}
+	if declaredKind(typ) == "BOOLEAN" {
+		return "INTEGER"
+	}
+	switch affinity := columnAffinity(typ); affinity {
+	case "INTEGER", "TEXT", "REAL":
+		return affinity
+	case "BLOB":
+		if strings.TrimSpace(typ) == "" {
+			return "ANY"
+		}
+		return "BLOB"
+	default:
+		return "ANY"
+	}
+}
+
+// ConvertToStrict 通过重建把表转换为 STRICT 表, types 可指定列的目标类型, 未指定的按 strictType 选择;
+// 存在无法存入目标类型的值时不能转换
+func ConvertToStrict(table string, types map[string]string, dryRun bool) (*StrictConversionResult, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	if err := ensureTable(table); err != nil {
+		return nil, err
+	}
+	ddl, err := loadTableDDL(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	if isStrictTable(ddl) {
+		return nil, fmt.Errorf("table %s is already STRICT", table)
+	}
+	for name := range types {
+		if ddl.Column(name) == nil {
+			return nil, fmt.Errorf("no such column: %s.%s", table, name)
+		}
+	}
+
+	result := &StrictConversionResult{Columns: []*ConversionPreview{}}
+	for _, col := range ddl.Columns() {
+		target := strictType(col.Type)
+		for name, typ := range types {
+			if strings.EqualFold(name, col.Name) {
+				target = strings.ToUpper(strings.TrimSpace(typ))
+			}
+		}
+		if !strictTypes[target] {
+			return nil, fmt.Errorf("column %s: type %s is not allowed in a STRICT table", col.Name, target)
+		}
+		preview, err := previewConversion(table, col, target, true)
+		if err != nil {
+			return nil, err
+		}
+		result.Columns = append(result.Columns, preview)
+		result.Failed += preview.Failed
+		if !strings.EqualFold(col.Type, target) {
+			col.SetType(target)
+		}
+	}
+	if result.Failed > 0 && !dryRun {
+		return result, fmt.Errorf("%d values cannot be stored in STRICT columns, fix them or choose other types", result.Failed)
+	}
+
+	if ddl.Options == "" {
+		ddl.Options = "STRICT"
+	} else {
+		ddl.Options += ", STRICT"
+	}
+	result.Rebuild, err = rebuildTable(table, ddlRebuildTarget(ddl), dryRun)
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+)
+
+// FieldError 单个字段的转换或校验错误
+type FieldError struct {
+	Field   string `json:"field"`
+	Value   any    `json:"value"`
+	Message string `json:"message"`
+}
+
+// FieldErrors 一行数据中所有字段的错误, 按字段名排序
+type FieldErrors []*FieldError
+
+func (e FieldErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, fe := range e {
+		parts[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// 日期类型可接受的输入格式
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02",
+	"20060102",
+}
+
+// 布尔类型可接受的字符串
+var boolStrings = map[string]int64{
+	"true": 1, "false": 0, "t": 1, "f": 0, "yes": 1, "no": 0, "y": 1, "n": 0, "on": 1, "off": 0, "1": 1, "0": 0,
+}
+
+// declaredKind 在亲和性之外区分布尔和日期, SQLite 中它们都是 NUMERIC 亲和性
+func declaredKind(typ string) string {
+	upper := strings.ToUpper(typ)
+	switch {
+	case strings.HasPrefix(upper, "BOOL"):
+		return "BOOLEAN"
+	case upper == "DATE":
+		return "DATE"
+	case strings.Contains(upper, "DATETIME"), strings.Contains(upper, "TIMESTAMP"):
+		return "DATETIME"
+	default:
+		return columnAffinity(typ)
+	}
+}
+
+// coerceRow 按列的声明类型转换一行数据, 原地替换转换后的值, 返回所有无法转换的字段
+// 非 STRICT 表中无法无损转换的值按原样保存(与 SQLite 的亲和性规则一致), 只有布尔和日期列报错
+func coerceRow(columns map[string]models.ColumnInfo, data map[string]any, strict bool) FieldErrors {
+	var errs FieldErrors
+	for k, v := range data {
+		col, ok := columns[k]
+		if !ok || col.Generated != "" {
+			continue
+		}
+		converted, err := coerceValue(col.Type, v, strict)
+		if err != nil {
+			errs = append(errs, &FieldError{Field: k, Value: v, Message: err.Error()})
+			continue
+		}
+		data[k] = converted
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// coerceValue 把 JSON/CSV 解析出的值转换为适合列声明类型的值
+// 布尔、日期列和 STRICT 表的数值列中空字符串视为 NULL
+func coerceValue(typ string, v any, strict bool) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	kind := declaredKind(typ)
+	explicit := kind == "BOOLEAN" || kind == "DATE" || kind == "DATETIME"
+	numeric := kind == "INTEGER" || kind == "REAL" || kind == "NUMERIC"
+	if s, ok := v.(string); ok && (explicit || (strict && numeric)) {
+		if s = strings.TrimSpace(s); s == "" {
+			return nil, nil
+		}
+		v = s
+	}
+	switch kind {
+	case "BOOLEAN":
+		return toBool(v)
+	case "DATE", "DATETIME":
+		return toDate(v, kind == "DATE")
+	}
+
+	converted, err := convertAffinity(kind, v)
+	if err == nil {
+		return converted, nil
+	}
+	if strict {
+		return nil, err
+	}
+	return plainValue(v)
+}
+
+// convertAffinity 按列亲和性无损转换, 无法转换时返回错误
+func convertAffinity(affinity string, v any) (any, error) {
+	switch affinity {
+	case "INTEGER":
+		return toInteger(v)
+	case "REAL":
+		f, err := toFloat(v)
+		if err != nil {
+			return nil, err
+		}
+		return f, nil
+	case "NUMERIC":
+		if i, err := toInteger(v); err == nil {
+			return i, nil
+		}
+		return toFloat(v)
+	case "TEXT":
+		return toText(v)
+	default:
+		// BLOB 亲和性(包括未声明类型和 ANY)不做转换
+		return plainValue(v)
+	}
+}
+
+// plainValue 把 JSON 解析出的值转为可绑定的参数: json.Number 转为整数或浮点数, 对象和数组保存为 JSON 文本
+func plainValue(v any) (any, error) {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		return val.Float64()
+	case map[string]any, []any:
+		return toText(val)
+	}
+	return v, nil
+}
+
+func toInteger(v any) (int64, error) {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		if i, ok := exactInteger(val); ok {
+			return i, nil
+		}
+		return 0, fmt.Errorf("%v is not an integer", val)
+	case json.Number:
+		return toInteger(val.String())
+	case string:
+		if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(val, 64); err == nil {
+			if i, ok := exactInteger(f); ok {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("'%s' is not an integer", val)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to integer", v)
+	}
+}
+
+// exactInteger 浮点数是整数且在 int64 范围内时返回对应整数, 超出范围的转换会溢出
+func exactInteger(f float64) (int64, bool) {
+	if f != math.Trunc(f) || f < -(1<<63) || f >= 1<<63 {
+		return 0, false
+	}
+	return int64(f), true
+}
+
+func toFloat(v any) (float64, error) {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case float64:
+		return val, nil
+	case json.Number:
+		return toFloat(val.String())
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not a number", val)
+		}
+		return f, nil
+	default:
+		return 0, fmt.Errorf("cannot convert %T to number", v)
+	}
+}
+
+func toBool(v any) (int64, error) {
+	switch val := v.(type) {
+	case bool:
+		return toInteger(val)
+	case float64, json.Number:
+		i, err := toInteger(val)
+		if err != nil || (i != 0 && i != 1) {
+			return 0, fmt.Errorf("%v is not a boolean", val)
+		}
+		return i, nil
+	case string:
+		if i, ok := boolStrings[strings.ToLower(val)]; ok {
+			return i, nil
+		}
+		return 0, fmt.Errorf("'%s' is not a boolean", val)
+	default:
+		return 0, fmt.Errorf("cannot convert %T to boolean", v)
+	}
+}
+
+// toDate 日期字符串统一为 ISO-8601 文本, 数字按 Unix 时间戳原样保存
+func toDate(v any, dateOnly bool) (any, error) {
+	switch val := v.(type) {
+	case float64, json.Number:
+		return toFloat(val)
+	case string:
+		for _, layout := range dateLayouts {
+			t, err := time.Parse(layout, val)
+			if err != nil {
+				continue
+			}
+			if dateOnly {
+				return t.Format("2006-01-02"), nil
+			}
+			// SQLite 的日期函数按 UTC 处理, 保留小数秒
+			return t.UTC().Format("2006-01-02 15:04:05.999999999"), nil
+		}
+		return nil, fmt.Errorf("'%s' is not a valid date", val)
+	default:
+		return nil, fmt.Errorf("cannot convert %T to date", v)
+	}
+}
+
+func toText(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64), nil
+	case json.Number:
+		return val.String(), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	default:
+		b, err := json.Marshal(val)
+		if err != nil {
+			return "", fmt.Errorf("cannot convert %T to text", v)
+		}
+		return string(b), nil
+	}
+}
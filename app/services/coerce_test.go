@@ -0,0 +1,98 @@
+package services
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToInteger(t *testing.T) {
+	tests := []struct {
+		in      any
+		want    int64
+		wantErr bool
+	}{
+		{true, 1, false},
+		{float64(42), 42, false},
+		{1.5, 0, true},
+		{1e20, 0, true},
+		{-1e20, 0, true},
+		{float64(-1 << 63), -1 << 63, false},
+		{"123", 123, false},
+		{"-9223372036854775808", -1 << 63, false},
+		{"9223372036854775808", 0, true},
+		{"1e3", 1000, false},
+		{"1e300", 0, true},
+		{"NaN", 0, true},
+		{"abc", 0, true},
+		{json.Number("7"), 7, false},
+		{json.Number("1e20"), 0, true},
+		{[]any{1}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := toInteger(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("toInteger(%#v) = %d, %v; want %d, err %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestToDate(t *testing.T) {
+	tests := []struct {
+		in       any
+		dateOnly bool
+		want     any
+		wantErr  bool
+	}{
+		{"2024-03-01", true, "2024-03-01", false},
+		{"2024/03/01", true, "2024-03-01", false},
+		{"20240301", false, "2024-03-01 00:00:00", false},
+		{"2024-03-01 10:20:30", false, "2024-03-01 10:20:30", false},
+		{"2024-03-01 10:20:30.125", false, "2024-03-01 10:20:30.125", false},
+		{"2024-03-01T10:20:30.5Z", false, "2024-03-01 10:20:30.5", false},
+		{"2024-03-01T10:20:30.123456+08:00", false, "2024-03-01 02:20:30.123456", false},
+		{"2024-03-01T10:20:30", false, "2024-03-01 10:20:30", false},
+		{float64(1700000000), false, float64(1700000000), false},
+		{"not a date", false, nil, true},
+		{true, false, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := toDate(tt.in, tt.dateOnly)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("toDate(%#v, %v) = %#v, %v; want %#v, err %v", tt.in, tt.dateOnly, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestCoerceValue(t *testing.T) {
+	tests := []struct {
+		typ     string
+		in      any
+		strict  bool
+		want    any
+		wantErr bool
+	}{
+		{"INTEGER", "12", false, int64(12), false},
+		{"INTEGER", 1e20, false, 1e20, false},
+		{"INTEGER", 1e20, true, nil, true},
+		{"INTEGER", "1e300", false, "1e300", false},
+		{"INTEGER", "1e300", true, nil, true},
+		{"INTEGER", "", false, "", false},
+		{"INTEGER", "", true, nil, false},
+		{"NUMERIC", 1e20, false, 1e20, false},
+		{"REAL", "1.5", true, 1.5, false},
+		{"TEXT", float64(3), false, "3", false},
+		{"BOOLEAN", "yes", false, int64(1), false},
+		{"BOOLEAN", "maybe", false, nil, true},
+		{"BOOLEAN", "", false, nil, false},
+		{"DATE", "", false, nil, false},
+		{"VARCHAR(10)", json.Number("5"), false, "5", false},
+		{"", json.Number("5"), false, int64(5), false},
+		{"", map[string]any{"a": float64(1)}, false, `{"a":1}`, false},
+	}
+	for _, tt := range tests {
+		got, err := coerceValue(tt.typ, tt.in, tt.strict)
+		if (err != nil) != tt.wantErr || !tt.wantErr && got != tt.want {
+			t.Errorf("coerceValue(%q, %#v, %v) = %#v, %v; want %#v, err %v", tt.typ, tt.in, tt.strict, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
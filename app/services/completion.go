@@ -0,0 +1,287 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// CompletionColumn 补全用的列信息
+type CompletionColumn struct {
+	Name string `db:"name" json:"name"`
+	Type string `db:"type" json:"type"`
+}
+
+// CompletionTable 补全用的表/视图信息
+type CompletionTable struct {
+	Name    string             `json:"name"`
+	Type    string             `json:"type"` // table / view
+	Columns []CompletionColumn `json:"columns"`
+}
+
+// CompletionIndex 补全用的索引信息
+type CompletionIndex struct {
+	Name  string `db:"name" json:"name"`
+	Table string `db:"tbl_name" json:"table"`
+}
+
+// Completions 编辑器自动补全所需的全部元数据
+type Completions struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	Tables        []CompletionTable `json:"tables"`
+	Indexes       []CompletionIndex `json:"indexes"`
+	Keywords      []string          `json:"keywords"`
+	Functions     []string          `json:"functions"`
+}
+
+// Suggestion 一条补全建议
+type Suggestion struct {
+	Label  string `json:"label"`
+	Kind   string `json:"kind"`             // table / view / column / alias / function / keyword
+	Detail string `json:"detail,omitempty"` // 列类型或所属表
+	Score  int    `json:"score"`
+}
+
+// CompleteResult 上下文补全结果
+type CompleteResult struct {
+	Prefix      string        `json:"prefix"`
+	From        int           `json:"from"` // 被替换文本的起始位置(字节偏移)
+	Context     string        `json:"context"`
+	Suggestions []*Suggestion `json:"suggestions"`
+}
+
+// sqliteKeywords SQLite 关键字
+var sqliteKeywords = []string{
+	"ABORT", "ACTION", "ADD", "AFTER", "ALL", "ALTER", "ALWAYS", "ANALYZE", "AND", "AS", "ASC",
+	"ATTACH", "AUTOINCREMENT", "BEFORE", "BEGIN", "BETWEEN", "BY", "CASCADE", "CASE", "CAST",
+	"CHECK", "COLLATE", "COLUMN", "COMMIT", "CONFLICT", "CONSTRAINT", "CREATE", "CROSS", "CURRENT",
+	"CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP", "DATABASE", "DEFAULT", "DEFERRABLE",
+	"DEFERRED", "DELETE", "DESC", "DETACH", "DISTINCT", "DO", "DROP", "EACH", "ELSE", "END",
+	"ESCAPE", "EXCEPT", "EXCLUDE", "EXCLUSIVE", "EXISTS", "EXPLAIN", "FAIL", "FILTER", "FIRST",
+	"FOLLOWING", "FOR", "FOREIGN", "FROM", "FULL", "GENERATED", "GLOB", "GROUP", "GROUPS", "HAVING",
+	"IF", "IGNORE", "IMMEDIATE", "IN", "INDEX", "INDEXED", "INITIALLY", "INNER", "INSERT", "INSTEAD",
+	"INTERSECT", "INTO", "IS", "ISNULL", "JOIN", "KEY", "LAST", "LEFT", "LIKE", "LIMIT", "MATCH",
+	"MATERIALIZED", "NATURAL", "NO", "NOT", "NOTHING", "NOTNULL", "NULL", "NULLS", "OF", "OFFSET",
+	"ON", "OR", "ORDER", "OTHERS", "OUTER", "OVER", "PARTITION", "PLAN", "PRAGMA", "PRECEDING",
+	"PRIMARY", "QUERY", "RAISE", "RANGE", "RECURSIVE", "REFERENCES", "REGEXP", "REINDEX", "RELEASE",
+	"RENAME", "REPLACE", "RESTRICT", "RETURNING", "RIGHT", "ROLLBACK", "ROW", "ROWS", "SAVEPOINT",
+	"SELECT", "SET", "STRICT", "TABLE", "TEMP", "TEMPORARY", "THEN", "TIES", "TO", "TRANSACTION",
+	"TRIGGER", "UNBOUNDED", "UNION", "UNIQUE", "UPDATE", "USING", "VACUUM", "VALUES", "VIEW",
+	"VIRTUAL", "WHEN", "WHERE", "WINDOW", "WITH", "WITHOUT",
+}
+
+// 补全元数据缓存, 按 schema_version 失效
+var completionCache struct {
+	sync.Mutex
+	data *Completions
+}
+
+// GetCompletions 返回自动补全元数据, schema 未变化时使用缓存
+func GetCompletions() (*Completions, error) {
+	var version int
+	if err := utils.DB.Get(&version, "PRAGMA schema_version"); err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	completionCache.Lock()
+	defer completionCache.Unlock()
+	if completionCache.data != nil && completionCache.data.SchemaVersion == version {
+		return completionCache.data, nil
+	}
+
+	data := &Completions{
+		SchemaVersion: version,
+		Tables:        []CompletionTable{},
+		Indexes:       []CompletionIndex{},
+		Keywords:      sqliteKeywords,
+		Functions:     []string{},
+	}
+
+	var objects []struct {
+		Name string `db:"name"`
+		Type string `db:"type"`
+	}
+	err := utils.DB.Select(&objects, `
+		SELECT name, type
+		FROM sqlite_master
+		WHERE type IN ('table', 'view')
+			AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tables: %w", err)
+	}
+	for _, o := range objects {
+		var cols []CompletionColumn
+		if err := utils.DB.Select(&cols, "SELECT name, type FROM pragma_table_info(?) ORDER BY cid", o.Name); err != nil {
+			return nil, fmt.Errorf("failed to load columns of %s: %w", o.Name, err)
+		}
+		data.Tables = append(data.Tables, CompletionTable{Name: o.Name, Type: o.Type, Columns: cols})
+	}
+
+	err = utils.DB.Select(&data.Indexes, `
+		SELECT name, tbl_name
+		FROM sqlite_master
+		WHERE type = 'index'
+			AND name NOT LIKE 'sqlite_%'
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexes: %w", err)
+	}
+
+	// 部分编译选项下不支持 pragma_function_list, 忽略错误
+	var functions []string
+	if err := utils.DB.Select(&functions, "SELECT DISTINCT name FROM pragma_function_list ORDER BY name"); err == nil {
+		for _, fn := range functions {
+			if IsValidIdentifier(fn) {
+				data.Functions = append(data.Functions, fn)
+			}
+		}
+	}
+
+	completionCache.data = data
+	return data, nil
+}
+
+// 在这些关键字之后应补全表名
+var tableContextKeywords = map[string]bool{
+	"FROM": true, "JOIN": true, "INTO": true, "UPDATE": true, "TABLE": true,
+}
+
+// Complete 根据 SQL 文本和光标位置给出排序后的补全建议
+func Complete(sqlText string, cursor int) (*CompleteResult, error) {
+	if cursor < 0 || cursor > len(sqlText) {
+		cursor = len(sqlText)
+	}
+	meta, err := GetCompletions()
+	if err != nil {
+		return nil, err
+	}
+
+	// 光标前紧挨着的单词作为前缀, 按词法单元切分以支持中文等非 ASCII 名称
+	before := sqlText[:cursor]
+	prefix := ""
+	if tokens := tokenizeSQL(before); len(tokens) > 0 && tokens[len(tokens)-1].Kind == tokWord {
+		prefix = tokens[len(tokens)-1].Text
+	}
+	head := significantTokens(tokenizeSQL(before[:len(before)-len(prefix)]))
+	result := &CompleteResult{
+		Prefix:      prefix,
+		From:        cursor - len(prefix),
+		Suggestions: []*Suggestion{},
+	}
+
+	tables := make(map[string]*CompletionTable, len(meta.Tables))
+	for i := range meta.Tables {
+		tables[strings.ToLower(meta.Tables[i].Name)] = &meta.Tables[i]
+	}
+	aliases := parseTableAliases(significantTokens(tokenizeSQL(sqlText)))
+
+	add := func(label, kind, detail string, base int) {
+		score, ok := matchScore(label, prefix)
+		if !ok {
+			return
+		}
+		result.Suggestions = append(result.Suggestions, &Suggestion{Label: label, Kind: kind, Detail: detail, Score: base + score})
+	}
+	addColumns := func(t *CompletionTable, base int) {
+		for _, col := range t.Columns {
+			add(col.Name, "column", fmt.Sprintf("%s (%s)", col.Type, t.Name), base)
+		}
+	}
+
+	n := len(head)
+	switch {
+	case n >= 2 && head[n-1].IsOp(".") && head[n-2].IsIdent():
+		// alias.col: 只补全该表的列
+		result.Context = "column"
+		qualifier := strings.ToLower(head[n-2].Ident())
+		name := qualifier
+		if table, ok := aliases[qualifier]; ok {
+			name = strings.ToLower(table)
+		}
+		if t, ok := tables[name]; ok {
+			addColumns(t, 300)
+		}
+	case isTableContext(head):
+		result.Context = "table"
+		for _, t := range meta.Tables {
+			add(t.Name, t.Type, "", 300)
+		}
+	default:
+		result.Context = "expression"
+		// FROM 子句中出现的表的列优先
+		var inScope []string
+		for _, table := range aliases {
+			if !slices.Contains(inScope, strings.ToLower(table)) {
+				inScope = append(inScope, strings.ToLower(table))
+			}
+		}
+		sort.Strings(inScope)
+		for _, name := range inScope {
+			if t, ok := tables[name]; ok {
+				addColumns(t, 300)
+			}
+		}
+		for alias, table := range aliases {
+			if alias != strings.ToLower(table) {
+				add(alias, "alias", table, 250)
+			}
+		}
+		for _, fn := range meta.Functions {
+			add(fn, "function", "", 150)
+		}
+		for _, kw := range sqliteKeywords {
+			add(kw, "keyword", "", 100)
+		}
+		if len(inScope) == 0 {
+			for _, t := range meta.Tables {
+				add(t.Name, t.Type, "", 50)
+			}
+		}
+	}
+
+	sort.SliceStable(result.Suggestions, func(i, j int) bool {
+		a, b := result.Suggestions[i], result.Suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return strings.ToLower(a.Label) < strings.ToLower(b.Label)
+	})
+	if len(result.Suggestions) > 100 {
+		result.Suggestions = result.Suggestions[:100]
+	}
+	return result, nil
+}
+
+// isTableContext 判断光标前的关键字是否期望一个表名
+func isTableContext(head []sqlToken) bool {
+	if len(head) == 0 {
+		return false
+	}
+	last := head[len(head)-1]
+	return last.Kind == tokWord && tableContextKeywords[last.Upper()]
+}
+
+// matchScore 前缀匹配得分更高, 其次是包含匹配
+func matchScore(label, prefix string) (int, bool) {
+	if prefix == "" {
+		return 0, true
+	}
+	l, p := strings.ToLower(label), strings.ToLower(prefix)
+	switch {
+	case l == p:
+		return 40, true
+	case strings.HasPrefix(l, p):
+		return 30, true
+	case strings.Contains(l, p):
+		return 10, true
+	default:
+		return 0, false
+	}
+}
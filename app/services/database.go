@@ -165,7 +165,7 @@ type triggerSchemaItem struct {
 	SQL       string `db:"sql"`
 }
 
-// GetAllTriggers 查询所有触发器
+// GetAllTriggers 查询所有触发器, 包括已停用的
 func GetAllTriggers() ([]*models.Trigger, error) {
 	query := `
 			SELECT 
@@ -185,18 +185,14 @@ func GetAllTriggers() ([]*models.Trigger, error) {
 	// 转换为业务模型
 	triggers := make([]*models.Trigger, len(schemas))
 	for i, s := range schemas {
-		trigger := &models.Trigger{
-			Name:       s.Name,
-			Table:      s.TableName,
-			Definition: formatTriggerDefinition(s.SQL),
-			SQL:        s.SQL,
-		}
-		// 解析类型和时机
-		trigger.Type, trigger.Timing = parseTriggerTypeTiming(s.SQL)
-		triggers[i] = trigger
+		triggers[i] = newTriggerModel(s.Name, s.TableName, s.SQL)
 	}
-
-	return triggers, nil
+	// 已停用的触发器保存在附属存储中
+	disabled, err := getDisabledTriggers()
+	if err != nil {
+		return nil, err
+	}
+	return append(triggers, disabled...), nil
 }
 
 // triggerDetailSchema 用于映射单个触发器的查询结果
@@ -216,44 +212,15 @@ func GetTriggerByName(name string) (*models.Trigger, error) {
 	err := utils.DB.Get(&schema, query, name)
 	if err != nil {
 		if err == sql.ErrNoRows {
+			if disabled, _ := getDisabledTrigger(name); disabled != nil {
+				return disabled, nil
+			}
 			return nil, fmt.Errorf("触发器不存在: %s", name)
 		}
 		return nil, fmt.Errorf("查询触发器失败: %w", err)
 	}
 
-	trigger := &models.Trigger{
-		Name:       name,
-		Table:      schema.TableName,
-		SQL:        schema.SQL,
-		Definition: formatTriggerDefinition(schema.SQL),
-	}
-	trigger.Type, trigger.Timing = parseTriggerTypeTiming(schema.SQL)
-
-	return trigger, nil
-}
-
-// parseTriggerTypeTiming 从 SQL 中解析触发器类型和时机
-// 简化处理，支持常见的 BEFORE/AFTER/INSTEAD OF + INSERT/UPDATE/DELETE
-func parseTriggerTypeTiming(sql string) (action, timing string) {
-	sql = strings.ToUpper(sql)
-	if strings.Contains(sql, " INSTEAD OF ") {
-		timing = "INSTEAD OF"
-	} else if strings.Contains(sql, " BEFORE ") {
-		timing = "BEFORE"
-	} else {
-		timing = "AFTER" // 默认
-	}
-	switch {
-	case strings.Contains(sql, " INSERT "):
-		action = "INSERT"
-	case strings.Contains(sql, " UPDATE "):
-		action = "UPDATE"
-	case strings.Contains(sql, " DELETE "):
-		action = "DELETE"
-	default:
-		action = "UNKNOWN"
-	}
-	return action, timing
+	return newTriggerModel(name, schema.TableName, schema.SQL), nil
 }
 
 // formatTriggerDefinition 简化 SQL 显示（用于前端展示）
@@ -309,8 +276,13 @@ func classifySQL(sql string) string {
 	}
 }
 
-// ExecuteSQL 执行任意 SQL 语句，适用于管理工具
-func ExecuteSQL(sqlStr string, page, size int) *SQLResult {
+// ExecuteSQL 执行任意 SQL 语句，适用于管理工具, args 为可选的绑定参数
+func ExecuteSQL(sqlStr string, page, size int, args ...any) *SQLResult {
+	return executeSQL(utils.DB, sqlStr, page, size, args...)
+}
+
+// executeSQL 在指定的连接或事务上执行 SQL
+func executeSQL(db sqlx.Ext, sqlStr string, page, size int, args ...any) *SQLResult {
 	start := time.Now()
 	result := &SQLResult{
 		Duration: 0,
@@ -330,10 +302,10 @@ func ExecuteSQL(sqlStr string, page, size int) *SQLResult {
 	stmtType := classifySQL(sqlStr)
 	// 分页只对 SELECT 有效
 	if stmtType == "SELECT" {
-		return executeSelect(sqlStr, page, size, start)
+		return executeSelect(db, sqlStr, page, size, start, args...)
 	}
 	// 其他类型：INSERT/UPDATE/DELETE/DDL
-	return executeExec(sqlStr, stmtType, start)
+	return executeExec(db, sqlStr, stmtType, start, args...)
 }
 
 type Pagination struct {
@@ -367,7 +339,7 @@ func hasPagination(sql string) bool {
 	return limitRe.MatchString(sql) || offsetRe.MatchString(sql)
 }
 
-func executeSelect(sqlStr string, page, size int, start time.Time) *SQLResult {
+func executeSelect(db sqlx.Ext, sqlStr string, page, size int, start time.Time, args ...any) *SQLResult {
 	result := &SQLResult{
 		Type:     "query",
 		Page:     page,
@@ -404,7 +376,7 @@ func executeSelect(sqlStr string, page, size int, start time.Time) *SQLResult {
 	}
 
 	// 获取总数
-	if total, err := getCount(sqlStr); err == nil {
+	if total, err := getCount(db, sqlStr, args...); err == nil {
 		result.Total = total
 	}
 
@@ -415,7 +387,7 @@ func executeSelect(sqlStr string, page, size int, start time.Time) *SQLResult {
 
 	utils.GetLogger("").Debug("Executing paginated SQL", "sql", paginatedSQL)
 	// 执行查询
-	rows, err := utils.DB.Queryx(paginatedSQL)
+	rows, err := db.Queryx(paginatedSQL, args...)
 	if err != nil {
 		result.Error = fmt.Sprintf("execute failed: %v", err)
 		return result
@@ -457,7 +429,147 @@ func executeSelect(sqlStr string, page, size int, start time.Time) *SQLResult {
 	return result
 }
 
-func executeExec(sqlStr, stmtType string, start time.Time) *SQLResult {
+// 流式查询的行数硬上限, 避免一次性推送过多数据
+const maxStreamRows = 100000
+
+// 流式查询每推送多少行刷新一次缓冲区
+const streamFlushEvery = 200
+
+// StreamEvent 流式查询推送的单条消息
+type StreamEvent struct {
+	Type      string         `json:"type"` // columns / row / done / error
+	Columns   []string       `json:"columns,omitempty"`
+	Row       map[string]any `json:"row,omitempty"`
+	Count     int            `json:"count,omitempty"`     // 已推送行数
+	HasMore   bool           `json:"hasMore,omitempty"`   // 本页之后是否还有数据
+	Truncated bool           `json:"truncated,omitempty"` // 是否因达到 maxStreamRows 上限被截断
+	Duration  float64        `json:"duration,omitempty"`  // 执行毫秒
+	Error     string         `json:"error,omitempty"`
+}
+
+// writeStreamEvent 按格式(ndjson/sse)写出一条消息
+func writeStreamEvent(w io.Writer, format string, event *StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化消息失败: %w", err)
+	}
+	if format == "sse" {
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+	} else {
+		_, err = fmt.Fprintf(w, "%s\n", data)
+	}
+	return err
+}
+
+// flushStream 如果 writer 支持 Flush, 则刷新到客户端
+func flushStream(w io.Writer) error {
+	if f, ok := w.(interface{ Flush() error }); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// StreamSelect 流式执行 SELECT, 先推送列信息, 再边扫描边推送行数据
+// format 支持 ndjson 和 sse; size 大于 0 时作为行数上限, 但不超过 maxStreamRows
+// 返回最后推送的 done/error 消息, error 仅表示写出失败(如客户端断开)
+func StreamSelect(sqlStr string, page, size int, format string, w io.Writer, args ...any) (*StreamEvent, error) {
+	return streamSelect(utils.DB, sqlStr, page, size, format, w, args...)
+}
+
+// streamSelect 在指定的连接或事务上流式执行 SELECT
+func streamSelect(db sqlx.Queryer, sqlStr string, page, size int, format string, w io.Writer, args ...any) (*StreamEvent, error) {
+	start := time.Now()
+	fail := func(err error) (*StreamEvent, error) {
+		event := &StreamEvent{Type: "error", Error: err.Error(), Duration: float64(time.Since(start).Milliseconds())}
+		if werr := writeStreamEvent(w, format, event); werr != nil {
+			return event, werr
+		}
+		return event, flushStream(w)
+	}
+
+	sqlStr = strings.TrimRight(cleanSQL(sqlStr), ";")
+	if sqlStr == "" {
+		return fail(fmt.Errorf("SQL is null"))
+	}
+	if classifySQL(sqlStr) != "SELECT" {
+		return fail(fmt.Errorf("only SELECT allowed in stream mode"))
+	}
+
+	limit := maxStreamRows
+	if size > 0 && size < limit {
+		limit = size
+	}
+	offset := 0
+	if page > 1 && size > 0 {
+		offset = (page - 1) * size
+	}
+	// 多取一行, 用于判断是否还有更多数据
+	streamSQL := fmt.Sprintf("SELECT * FROM (%s) LIMIT %d OFFSET %d", sqlStr, limit+1, offset)
+	utils.GetLogger("").Debug("Executing stream SQL", "sql", streamSQL)
+
+	rows, err := db.Queryx(streamSQL, args...)
+	if err != nil {
+		return fail(fmt.Errorf("execute failed: %w", err))
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return fail(fmt.Errorf("get columns failed: %w", err))
+	}
+	if err := writeStreamEvent(w, format, &StreamEvent{Type: "columns", Columns: cols}); err != nil {
+		return nil, err
+	}
+	if err := flushStream(w); err != nil {
+		return nil, err
+	}
+
+	count := 0
+	hasMore := false
+	for rows.Next() {
+		if count >= limit {
+			hasMore = true
+			break
+		}
+		row := make(map[string]any)
+		if err := rows.MapScan(row); err != nil {
+			return fail(fmt.Errorf("scan row failed: %w", err))
+		}
+		// 处理 []byte 类型
+		for k, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[k] = string(b)
+			}
+		}
+		if err := writeStreamEvent(w, format, &StreamEvent{Type: "row", Row: row}); err != nil {
+			// 客户端断开, 停止扫描
+			return &StreamEvent{Type: "error", Count: count, Error: err.Error()}, err
+		}
+		count++
+		if count%streamFlushEvery == 0 {
+			if err := flushStream(w); err != nil {
+				return &StreamEvent{Type: "error", Count: count, Error: err.Error()}, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fail(fmt.Errorf("row iteration error: %w", err))
+	}
+
+	done := &StreamEvent{
+		Type:      "done",
+		Count:     count,
+		HasMore:   hasMore,
+		Truncated: hasMore && limit == maxStreamRows,
+		Duration:  float64(time.Since(start).Milliseconds()),
+	}
+	if err := writeStreamEvent(w, format, done); err != nil {
+		return done, err
+	}
+	return done, flushStream(w)
+}
+
+func executeExec(db sqlx.Execer, sqlStr, stmtType string, start time.Time, args ...any) *SQLResult {
 	result := &SQLResult{
 		Type:     "exec",
 		Duration: 0,
@@ -467,7 +579,7 @@ func executeExec(sqlStr, stmtType string, start time.Time) *SQLResult {
 		result.Duration = float64(time.Since(start).Milliseconds())
 	}()
 
-	res, err := utils.DB.Exec(sqlStr)
+	res, err := db.Exec(sqlStr, args...)
 	if err != nil {
 		result.Error = fmt.Sprintf("executed failed: %v", err)
 		return result
@@ -500,7 +612,7 @@ func cleanSQL(sql string) string {
 }
 
 // getCount 获取查询的总行数, 如果含有limit/offset, 去掉
-func getCount(sql string) (int64, error) {
+func getCount(db sqlx.Queryer, sql string, args ...any) (int64, error) {
 	if hasPagination(sql) {
 		// 去掉 LIMIT 和 OFFSET
 		sql = regexp.MustCompile(`(?i)\s+LIMIT\s+\d+`).ReplaceAllString(sql, "")
@@ -512,7 +624,7 @@ func getCount(sql string) (int64, error) {
 	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM (%s) AS _count", strings.TrimRight(sql, ";"))
 	utils.GetLogger("").Debug("Count SQL", "sql", countSQL)
 	var total int64
-	if err := utils.DB.Get(&total, countSQL); err != nil {
+	if err := sqlx.Get(db, &total, countSQL, args...); err != nil {
 		return -1, fmt.Errorf("count failed: %w", err)
 	}
 	return total, nil
@@ -520,8 +632,8 @@ func getCount(sql string) (int64, error) {
 
 // getColumnsFromQuery 获取查询的列名
 // 方法：执行一次干跑（带 LIMIT 0）
-func getColumnsFromQuery(sqlStr string) ([]string, error) {
-	rows, err := utils.DB.Queryx(fmt.Sprintf("SELECT * FROM (%s) AS t LIMIT 0", sqlStr))
+func getColumnsFromQuery(sqlStr string, args ...any) ([]string, error) {
+	rows, err := utils.DB.Queryx(fmt.Sprintf("SELECT * FROM (%s) AS t LIMIT 0", sqlStr), args...)
 	if err != nil {
 		return nil, fmt.Errorf("dry run failed: %w", err)
 	}
@@ -535,15 +647,15 @@ func getColumnsFromQuery(sqlStr string) ([]string, error) {
 
 // CreateSQLiteTable 根据请求创建表
 func CreateSQLiteTable(req *models.CreateTableRequest) error {
-	// 检查表名合法性（简单校验）
-	if !IsValidIdentifier(req.TableName) {
-		return fmt.Errorf("invalid table name: %s", req.TableName)
+	sql, err := CreateTableSQL(req)
+	if err != nil {
+		return err
 	}
 
-	// Step 1: 检查表是否已存在
+	// 检查表是否已存在
 	var exists bool
 	query := `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type='table' AND name=?)`
-	err := utils.DB.Get(&exists, query, req.TableName)
+	err = utils.DB.Get(&exists, query, req.TableName)
 	if err != nil {
 		return fmt.Errorf("failed to check if table exists: %w", err)
 	}
@@ -551,30 +663,59 @@ func CreateSQLiteTable(req *models.CreateTableRequest) error {
 		return fmt.Errorf("table %s already exists", req.TableName)
 	}
 
-	sql := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" (
-			"id" INTEGER PRIMARY KEY AUTOINCREMENT
-	)`, req.TableName)
-
 	_, err = utils.DB.Exec(sql)
 	return err
 }
 
+// CreateTableSQL 生成建表 SQL, 未指定列时只包含自增 id
+func CreateTableSQL(req *models.CreateTableRequest) (string, error) {
+	if len(req.Columns) == 0 {
+		req.Columns = defaultTableColumns()
+	}
+	return BuildCreateTableSQL(req)
+}
+
+// DropSQLiteTable 在事务中删除表及其全文索引, 并清除该表已停用的触发器
 func DropSQLiteTable(tableName string) error {
+	statements, err := DropTableSQL(tableName)
+	if err != nil {
+		return err
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := execStatements(tx, statements); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return dropDisabledTriggers(tableName)
+}
+
+// DropTableSQL 生成删除表的 SQL, 表有全文索引时先删除索引虚拟表和同步触发器
+func DropTableSQL(tableName string) ([]string, error) {
 	// 检查表名合法性（简单校验）
 	if !IsValidIdentifier(tableName) {
-		return fmt.Errorf("invalid table name: %s", tableName)
+		return nil, fmt.Errorf("invalid table name: %s", tableName)
 	}
-
-	sql := fmt.Sprintf(`DROP TABLE "%s"`, tableName)
-
-	_, err := utils.DB.Exec(sql)
-	return err
+	var statements []string
+	index, err := getFTSIndex(utils.DB, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if index != nil {
+		statements = ftsDropStatements(tableName)
+	}
+	return append(statements, "DROP TABLE "+utils.QuoteIdentifier(tableName)), nil
 }
 
-// 导出查询数据
-func ExportQuery(sql string, page, size int, fileType string, w io.Writer) error {
+// 导出查询数据, args 为可选的绑定参数
+func ExportQuery(sql string, page, size int, fileType string, w io.Writer, args ...any) error {
 	// 获取列名（通过 EXPLAIN QUERY PLAN 或干跑查询）
-	cols, err := getColumnsFromQuery(sql)
+	cols, err := getColumnsFromQuery(sql, args...)
 	if err != nil {
 		return fmt.Errorf("获取列名失败: %w", err)
 	}
@@ -583,7 +724,7 @@ func ExportQuery(sql string, page, size int, fileType string, w io.Writer) error
 	paginatedSQL := fmt.Sprintf("%s LIMIT %d OFFSET %d", sql, size+1, offset)
 
 	// 使用 sqlx 查询
-	rows, err := utils.DB.Queryx(paginatedSQL)
+	rows, err := utils.DB.Queryx(paginatedSQL, args...)
 	if err != nil {
 		return fmt.Errorf("执行查询失败: %w", err)
 	}
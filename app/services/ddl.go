@@ -0,0 +1,370 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+)
+
+// 表级约束的起始关键字
+var tableConstraintKeywords = []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "CHECK", "FOREIGN"}
+
+// 列定义中类型名之后可能出现的约束关键字
+var columnConstraintKeywords = []string{
+	"CONSTRAINT", "PRIMARY", "NOT", "NULL", "UNIQUE", "CHECK", "DEFAULT", "COLLATE",
+	"REFERENCES", "GENERATED", "AS",
+}
+
+// ddlItem CREATE TABLE 括号内的一项: 列定义或表级约束
+type ddlItem struct {
+	Text       string // 原始文本, 已去掉首尾空白
+	Constraint bool   // 是否为表级约束
+	Name       string // 列名(去掉引号), 仅列定义有效
+	Type       string // 声明的类型, 仅列定义有效
+	typeStart  int    // 类型在 Text 中的字节范围
+	typeEnd    int
+}
+
+// createTableDDL 拆分后的 CREATE TABLE 语句
+type createTableDDL struct {
+	Items   []*ddlItem
+	Options string // 右括号之后的表选项, 如 STRICT, WITHOUT ROWID
+}
+
+// Columns 返回列定义
+func (d *createTableDDL) Columns() []*ddlItem {
+	var cols []*ddlItem
+	for _, item := range d.Items {
+		if !item.Constraint {
+			cols = append(cols, item)
+		}
+	}
+	return cols
+}
+
+// Column 按名称查找列定义(忽略大小写)
+func (d *createTableDDL) Column(name string) *ddlItem {
+	for _, item := range d.Items {
+		if !item.Constraint && strings.EqualFold(item.Name, name) {
+			return item
+		}
+	}
+	return nil
+}
+
+// Render 以新表名生成 CREATE TABLE 语句
+func (d *createTableDDL) Render(quotedName string) string {
+	defs := make([]string, len(d.Items))
+	for i, item := range d.Items {
+		defs[i] = item.Text
+	}
+	sql := fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", quotedName, strings.Join(defs, ",\n\t"))
+	if d.Options != "" {
+		sql += " " + d.Options
+	}
+	return sql
+}
+
+// SetType 修改列定义中的类型名, 其余约束保持不变
+func (item *ddlItem) SetType(typ string) {
+	insert, start := typ, item.typeStart
+	if item.typeStart == item.typeEnd {
+		// 原来没有声明类型, 在列名后插入
+		insert = " " + typ
+		start++
+	}
+	item.Text = item.Text[:item.typeStart] + insert + item.Text[item.typeEnd:]
+	item.typeStart, item.typeEnd = start, start+len(typ)
+	item.Type = typ
+}
+
+// parseCreateTable 将 CREATE TABLE 语句拆分为列定义、表级约束和表选项
+func parseCreateTable(sqlText string) (*createTableDDL, error) {
+	tokens := tokenizeSQL(sqlText)
+	open := -1
+	for i, t := range tokens {
+		if t.IsOp("(") {
+			open = i
+			break
+		}
+		if t.Is("AS") {
+			return nil, fmt.Errorf("CREATE TABLE ... AS SELECT is not supported")
+		}
+	}
+	if open < 0 {
+		return nil, fmt.Errorf("invalid CREATE TABLE statement")
+	}
+
+	ddl := &createTableDDL{}
+	depth := 0
+	itemStart := tokens[open].Pos + 1
+	for i := open; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+			if depth == 0 {
+				ddl.Items = append(ddl.Items, newDDLItem(sqlText[itemStart:t.Pos]))
+				ddl.Options = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sqlText[t.Pos+1:]), ";"))
+				return ddl, nil
+			}
+		case t.IsOp(",") && depth == 1:
+			ddl.Items = append(ddl.Items, newDDLItem(sqlText[itemStart:t.Pos]))
+			itemStart = t.Pos + 1
+		}
+	}
+	return nil, fmt.Errorf("unbalanced parentheses in CREATE TABLE statement")
+}
+
+// newDDLItem 解析括号内的一项, 识别列名和类型
+func newDDLItem(text string) *ddlItem {
+	item := &ddlItem{Text: strings.TrimSpace(text)}
+	sig := significantTokens(tokenizeSQL(item.Text))
+	if len(sig) == 0 {
+		return item
+	}
+	if sig[0].Is(tableConstraintKeywords...) {
+		item.Constraint = true
+		return item
+	}
+	item.Name = sig[0].Ident()
+	item.typeStart = sig[0].Pos + len(sig[0].Text)
+	item.typeEnd = item.typeStart
+	// 类型名由若干单词和可选的 (n) 或 (n, m) 组成
+	depth := 0
+	for _, t := range sig[1:] {
+		if depth == 0 && t.Is(columnConstraintKeywords...) {
+			break
+		}
+		switch {
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+		}
+		if item.typeEnd == sig[0].Pos+len(sig[0].Text) {
+			item.typeStart = t.Pos
+		}
+		item.typeEnd = t.Pos + len(t.Text)
+		if depth == 0 && t.IsOp(")") {
+			break
+		}
+	}
+	item.Type = item.Text[item.typeStart:item.typeEnd]
+	return item
+}
+
+// parseTableDefinition 解析普通表的 CREATE TABLE 语句, 视图、虚拟表或无法解析时返回 nil
+func parseTableDefinition(sqlText string) *createTableDDL {
+	sig := significantTokens(tokenizeSQL(sqlText))
+	i := 1
+	if i < len(sig) && sig[i].Is("TEMP", "TEMPORARY") {
+		i++
+	}
+	if len(sig) <= i || !sig[0].Is("CREATE") || !sig[i].Is("TABLE") {
+		return nil
+	}
+	ddl, err := parseCreateTable(sqlText)
+	if err != nil {
+		return nil
+	}
+	return ddl
+}
+
+// ColumnConstraints 解析列定义中类型名之后的约束
+func (item *ddlItem) ColumnConstraints() []models.Constraint {
+	if item.Constraint {
+		return nil
+	}
+	sig := significantTokens(tokenizeSQL(item.Text))
+	i := 0
+	for i < len(sig) && sig[i].Pos < item.typeEnd {
+		i++
+	}
+	return parseConstraints(item.Text, sig[i:])
+}
+
+// TableConstraint 解析表级约束
+func (item *ddlItem) TableConstraint() *models.Constraint {
+	if !item.Constraint {
+		return nil
+	}
+	constraints := parseConstraints(item.Text, significantTokens(tokenizeSQL(item.Text)))
+	if len(constraints) == 0 {
+		return nil
+	}
+	return &constraints[0]
+}
+
+// OptionList 拆分表选项, 如 STRICT、WITHOUT ROWID
+func (d *createTableDDL) OptionList() []string {
+	var options []string
+	for _, opt := range strings.Split(d.Options, ",") {
+		if words := strings.Fields(opt); len(words) > 0 {
+			options = append(options, strings.ToUpper(strings.Join(words, " ")))
+		}
+	}
+	return options
+}
+
+// parseConstraints 依次解析 [CONSTRAINT name] 开头的约束, 列约束和表级约束共用
+func parseConstraints(text string, sig []sqlToken) []models.Constraint {
+	var constraints []models.Constraint
+	for i := 0; i < len(sig); i++ {
+		start := i
+		var c models.Constraint
+		if sig[i].Is("CONSTRAINT") && i+1 < len(sig) {
+			c.Name = sig[i+1].Ident()
+			i += 2
+			if i >= len(sig) {
+				break
+			}
+		}
+		t := sig[i]
+		end := i
+		switch {
+		case t.Is("PRIMARY"):
+			c.Type = "PRIMARY KEY"
+			if i+1 < len(sig) && sig[i+1].Is("KEY") {
+				end++
+			}
+			c.Columns = groupColumns(sig, end+1)
+			end = constraintTail(sig, end)
+		case t.Is("UNIQUE"):
+			c.Type = "UNIQUE"
+			c.Columns = groupColumns(sig, end+1)
+			end = constraintTail(sig, end)
+		case t.Is("NOT") && i+1 < len(sig) && sig[i+1].Is("NULL"):
+			c.Type = "NOT NULL"
+			end = constraintTail(sig, i+1)
+		case t.Is("NULL"):
+			c.Type = "NULL"
+		case t.Is("CHECK"):
+			c.Type = "CHECK"
+			if i+1 < len(sig) && sig[i+1].IsOp("(") {
+				end = groupEnd(sig, i+1)
+				c.Expr = groupText(text, sig, i+1, end)
+			}
+		case t.Is("DEFAULT") && i+1 < len(sig):
+			c.Type = "DEFAULT"
+			end = i + 1
+			switch {
+			case sig[end].IsOp("("):
+				end = groupEnd(sig, end)
+			case sig[end].IsOp("+", "-") && end+1 < len(sig):
+				end++
+			}
+			c.Expr = text[sig[i+1].Pos : sig[end].Pos+len(sig[end].Text)]
+		case t.Is("COLLATE") && i+1 < len(sig):
+			c.Type = "COLLATE"
+			end = i + 1
+			c.Expr = sig[end].Ident()
+		case t.Is("REFERENCES"):
+			c.Type = "REFERENCES"
+			end = foreignKeyClauseEnd(sig, i)
+		case t.Is("FOREIGN"):
+			c.Type = "FOREIGN KEY"
+			for end < len(sig)-1 && !sig[end].Is("REFERENCES") {
+				end++
+			}
+			c.Columns = foreignKeyColumns(sig[i:end])
+			end = foreignKeyClauseEnd(sig, end)
+		case t.Is("GENERATED", "AS"):
+			// [GENERATED ALWAYS] AS (expr) [VIRTUAL | STORED]
+			c.Type = "GENERATED"
+			for end < len(sig)-1 && !sig[end].IsOp("(") {
+				end++
+			}
+			open := end
+			end = groupEnd(sig, open)
+			c.Expr = groupText(text, sig, open, end)
+			if end+1 < len(sig) && sig[end+1].Is("VIRTUAL", "STORED") {
+				end++
+			}
+		default:
+			// 无法识别的内容单独记录, 避免影响后续约束
+			c.Type = t.Upper()
+		}
+		if end >= len(sig) {
+			end = len(sig) - 1
+		}
+		c.SQL = text[sig[start].Pos : sig[end].Pos+len(sig[end].Text)]
+		constraints = append(constraints, c)
+		i = end
+	}
+	return constraints
+}
+
+// constraintTail 跳过 PRIMARY KEY / UNIQUE / NOT NULL 之后的列清单、排序、ON CONFLICT 和 AUTOINCREMENT, 返回最后一个词法单元的下标
+func constraintTail(sig []sqlToken, end int) int {
+	for end+1 < len(sig) {
+		next := sig[end+1]
+		switch {
+		case next.IsOp("("):
+			end = groupEnd(sig, end+1)
+		case next.Is("ASC", "DESC", "AUTOINCREMENT"):
+			end++
+		case next.Is("ON") && end+3 < len(sig) && sig[end+2].Is("CONFLICT"):
+			end += 3
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// groupEnd 返回与 sig[open] 处左括号匹配的右括号下标, 不匹配时返回最后一个下标
+func groupEnd(sig []sqlToken, open int) int {
+	depth := 0
+	for i := open; i < len(sig); i++ {
+		switch {
+		case sig[i].IsOp("("):
+			depth++
+		case sig[i].IsOp(")"):
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(sig) - 1
+}
+
+// groupText 返回括号内的原文
+func groupText(text string, sig []sqlToken, open, close int) string {
+	if close <= open || !sig[close].IsOp(")") {
+		return ""
+	}
+	return strings.TrimSpace(text[sig[open].Pos+1 : sig[close].Pos])
+}
+
+// groupColumns 返回 sig[open] 处括号内每项开头的列名, 不是括号时返回 nil
+func groupColumns(sig []sqlToken, open int) []string {
+	if open >= len(sig) || !sig[open].IsOp("(") {
+		return nil
+	}
+	var columns []string
+	depth := 0
+	expectName := true
+	for i := open; i < len(sig); i++ {
+		t := sig[i]
+		switch {
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+			if depth == 0 {
+				return columns
+			}
+		case depth == 1 && t.IsOp(","):
+			expectName = true
+		case depth == 1 && expectName && t.IsIdent():
+			columns = append(columns, t.Ident())
+			expectName = false
+		}
+	}
+	return columns
+}
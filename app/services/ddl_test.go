@@ -0,0 +1,151 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+)
+
+func TestParseCreateTable(t *testing.T) {
+	sql := `CREATE TABLE "订单" (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		"order-date" TEXT NOT NULL DEFAULT (date('now')),
+		amount DECIMAL(10, 2) CHECK (amount >= 0),
+		note, -- no type
+		[kind] VARCHAR (20) COLLATE NOCASE,
+		CONSTRAINT uq UNIQUE (note, kind),
+		FOREIGN KEY (id) REFERENCES other(id)
+	) STRICT, WITHOUT ROWID;`
+	ddl, err := parseCreateTable(sql)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type column struct{ name, typ string }
+	var got []column
+	for _, item := range ddl.Columns() {
+		got = append(got, column{item.Name, item.Type})
+	}
+	want := []column{{"id", "INTEGER"}, {"order-date", "TEXT"}, {"amount", "DECIMAL(10, 2)"}, {"note", ""}, {"kind", "VARCHAR (20)"}}
+	if !slices.Equal(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+	if n := len(ddl.Items) - len(ddl.Columns()); n != 2 {
+		t.Errorf("%d table constraints, want 2", n)
+	}
+	if opts := ddl.OptionList(); !slices.Equal(opts, []string{"STRICT", "WITHOUT ROWID"}) {
+		t.Errorf("OptionList() = %v", opts)
+	}
+	if !isStrictTable(ddl) {
+		t.Error("isStrictTable() = false")
+	}
+
+	ddl.Column("AMOUNT").SetType("REAL")
+	ddl.Column("note").SetType("TEXT")
+	if got := ddl.Column("amount").Text; got != "amount REAL CHECK (amount >= 0)" {
+		t.Errorf("SetType on typed column: %q", got)
+	}
+	if got := ddl.Column("note").Text; got != "note TEXT" {
+		t.Errorf("SetType on untyped column: %q", got)
+	}
+	if rendered, err := parseCreateTable(ddl.Render(`"t2"`)); err != nil || len(rendered.Items) != len(ddl.Items) || rendered.Options != ddl.Options {
+		t.Errorf("Render() does not round-trip: %v", err)
+	}
+
+	for _, bad := range []string{"CREATE TABLE t AS SELECT 1", "CREATE TABLE t (a, b", "CREATE TABLE t"} {
+		if _, err := parseCreateTable(bad); err == nil {
+			t.Errorf("parseCreateTable(%q) succeeded, want error", bad)
+		}
+	}
+	for _, other := range []string{"CREATE VIEW v AS SELECT 1", `CREATE VIRTUAL TABLE f USING fts5(a)`} {
+		if parseTableDefinition(other) != nil {
+			t.Errorf("parseTableDefinition(%q) != nil", other)
+		}
+	}
+}
+
+func TestColumnAffinity(t *testing.T) {
+	tests := map[string]string{
+		"INTEGER": "INTEGER", "BIGINT": "INTEGER", "POINT": "INTEGER",
+		"VARCHAR(10)": "TEXT", "CLOB": "TEXT", "nchar": "TEXT",
+		"BLOB": "BLOB", "": "BLOB",
+		"REAL": "REAL", "DOUBLE PRECISION": "REAL", "FLOAT": "REAL",
+		"DECIMAL(10,2)": "NUMERIC", "BOOLEAN": "NUMERIC", "DATE": "NUMERIC",
+	}
+	for typ, want := range tests {
+		if got := columnAffinity(typ); got != want {
+			t.Errorf("columnAffinity(%q) = %q, want %q", typ, got, want)
+		}
+	}
+}
+
+func TestParseConstraints(t *testing.T) {
+	tests := []struct {
+		item string
+		want []models.Constraint
+	}{
+		{
+			"id INTEGER PRIMARY KEY DESC ON CONFLICT REPLACE AUTOINCREMENT NOT NULL",
+			[]models.Constraint{
+				{Type: "PRIMARY KEY", SQL: "PRIMARY KEY DESC ON CONFLICT REPLACE AUTOINCREMENT"},
+				{Type: "NOT NULL", SQL: "NOT NULL"},
+			},
+		},
+		{
+			"price REAL CONSTRAINT positive CHECK (price > 0) DEFAULT -1 COLLATE NOCASE",
+			[]models.Constraint{
+				{Name: "positive", Type: "CHECK", Expr: "price > 0", SQL: "CONSTRAINT positive CHECK (price > 0)"},
+				{Type: "DEFAULT", Expr: "-1", SQL: "DEFAULT -1"},
+				{Type: "COLLATE", Expr: "NOCASE", SQL: "COLLATE NOCASE"},
+			},
+		},
+		{
+			"created TEXT DEFAULT (datetime('now', 'localtime')) NULL",
+			[]models.Constraint{
+				{Type: "DEFAULT", Expr: "(datetime('now', 'localtime'))", SQL: "DEFAULT (datetime('now', 'localtime'))"},
+				{Type: "NULL", SQL: "NULL"},
+			},
+		},
+		{
+			"user_id INT REFERENCES users(id) ON DELETE CASCADE UNIQUE",
+			[]models.Constraint{
+				{Type: "REFERENCES", SQL: "REFERENCES users(id) ON DELETE CASCADE"},
+				{Type: "UNIQUE", SQL: "UNIQUE"},
+			},
+		},
+		{
+			"total REAL GENERATED ALWAYS AS (price * qty) STORED",
+			[]models.Constraint{{Type: "GENERATED", Expr: "price * qty", SQL: "GENERATED ALWAYS AS (price * qty) STORED"}},
+		},
+		{
+			"CONSTRAINT pk PRIMARY KEY (a, \"b c\")",
+			[]models.Constraint{{Name: "pk", Type: "PRIMARY KEY", Columns: []string{"a", "b c"}, SQL: "CONSTRAINT pk PRIMARY KEY (a, \"b c\")"}},
+		},
+		{
+			"FOREIGN KEY (a, b) REFERENCES other (x, y) ON UPDATE SET NULL DEFERRABLE INITIALLY DEFERRED",
+			[]models.Constraint{{Type: "FOREIGN KEY", Columns: []string{"a", "b"},
+				SQL: "FOREIGN KEY (a, b) REFERENCES other (x, y) ON UPDATE SET NULL DEFERRABLE INITIALLY DEFERRED"}},
+		},
+	}
+	for _, tt := range tests {
+		item := newDDLItem(tt.item)
+		var got []models.Constraint
+		if item.Constraint {
+			if c := item.TableConstraint(); c != nil {
+				got = append(got, *c)
+			}
+		} else {
+			got = item.ColumnConstraints()
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("constraints of %q = %+v, want %+v", tt.item, got, tt.want)
+			continue
+		}
+		for i, w := range tt.want {
+			g := got[i]
+			if g.Name != w.Name || g.Type != w.Type || g.Expr != w.Expr || g.SQL != w.SQL || !slices.Equal(g.Columns, w.Columns) {
+				t.Errorf("constraint %d of %q = %+v, want %+v", i, tt.item, g, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// schemaObject sqlite_master 中的一个对象
+type schemaObject struct {
+	Type  string         `db:"type"`
+	Name  string         `db:"name"`
+	Table string         `db:"tbl_name"`
+	SQL   sql.NullString `db:"sql"`
+}
+
+// SchemaChange 一个对象的结构变化
+type SchemaChange struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Table  string `json:"table"`
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// SchemaDiff 执行前后的结构差异
+type SchemaDiff struct {
+	Added   []SchemaChange `json:"added"`
+	Removed []SchemaChange `json:"removed"`
+	Changed []SchemaChange `json:"changed"`
+}
+
+// DryRunResult 试运行结果, 所有修改均已回滚
+type DryRunResult struct {
+	SQL          []string    `json:"sql"`
+	RowsAffected int64       `json:"rowsAffected"`
+	Result       *SQLResult  `json:"result,omitempty"` // /db/query 的执行结果
+	SchemaDiff   *SchemaDiff `json:"schemaDiff"`
+	Duration     float64     `json:"duration"`
+}
+
+// 试运行使用的保存点名
+const dryRunSavepoint = "sqlite_web_dry_run"
+
+// loadSchemaObjects 读取当前 schema, 按 类型/名称 索引
+func loadSchemaObjects(db sqlx.Queryer) (map[string]schemaObject, []string, error) {
+	var objects []schemaObject
+	if err := sqlx.Select(db, &objects, "SELECT type, name, tbl_name, sql FROM sqlite_master ORDER BY type, name"); err != nil {
+		return nil, nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	m := make(map[string]schemaObject, len(objects))
+	keys := make([]string, 0, len(objects))
+	for _, o := range objects {
+		key := o.Type + "\x00" + o.Name
+		m[key] = o
+		keys = append(keys, key)
+	}
+	return m, keys, nil
+}
+
+// diffSchema 比较执行前后的 schema
+func diffSchema(before, after map[string]schemaObject, beforeKeys, afterKeys []string) *SchemaDiff {
+	diff := &SchemaDiff{Added: []SchemaChange{}, Removed: []SchemaChange{}, Changed: []SchemaChange{}}
+	for _, key := range afterKeys {
+		a := after[key]
+		b, ok := before[key]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, SchemaChange{Type: a.Type, Name: a.Name, Table: a.Table, After: a.SQL.String})
+		case b.SQL.String != a.SQL.String || b.Table != a.Table:
+			diff.Changed = append(diff.Changed, SchemaChange{Type: a.Type, Name: a.Name, Table: a.Table, Before: b.SQL.String, After: a.SQL.String})
+		}
+	}
+	for _, key := range beforeKeys {
+		if _, ok := after[key]; !ok {
+			b := before[key]
+			diff.Removed = append(diff.Removed, SchemaChange{Type: b.Type, Name: b.Name, Table: b.Table, Before: b.SQL.String})
+		}
+	}
+	return diff
+}
+
+// dryRun 在保存点中执行 fn, 记录 schema 差异和影响行数后回滚到保存点
+// 影响行数取 total_changes() 的差值, DDL 语句不会让 changes() 归零
+func dryRun(db sqlx.Ext, fn func(db sqlx.Ext) error) (*SchemaDiff, int64, error) {
+	if _, err := db.Exec("SAVEPOINT " + dryRunSavepoint); err != nil {
+		return nil, 0, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	defer func() {
+		_, _ = db.Exec("ROLLBACK TO " + dryRunSavepoint)
+		_, _ = db.Exec("RELEASE " + dryRunSavepoint)
+	}()
+
+	before, beforeKeys, err := loadSchemaObjects(db)
+	if err != nil {
+		return nil, 0, err
+	}
+	var changesBefore, changesAfter int64
+	if err := sqlx.Get(db, &changesBefore, "SELECT total_changes()"); err != nil {
+		return nil, 0, fmt.Errorf("failed to get changes: %w", err)
+	}
+	if err := fn(db); err != nil {
+		return nil, 0, err
+	}
+	if err := sqlx.Get(db, &changesAfter, "SELECT total_changes()"); err != nil {
+		return nil, 0, fmt.Errorf("failed to get changes: %w", err)
+	}
+	after, afterKeys, err := loadSchemaObjects(db)
+	if err != nil {
+		return nil, 0, err
+	}
+	return diffSchema(before, after, beforeKeys, afterKeys), changesAfter - changesBefore, nil
+}
+
+// withDryRunTx 在交互式事务(txID 非空)或新开的事务中试运行, 新开的事务最后整体回滚
+func withDryRunTx(txID string, fn func(db sqlx.Ext) error) (*SchemaDiff, int64, error) {
+	if txID != "" {
+		var diff *SchemaDiff
+		var changes int64
+		err := withTx(txID, func(s *txSession) error {
+			var err error
+			diff, changes, err = dryRun(s.tx, fn)
+			return err
+		})
+		return diff, changes, err
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	return dryRun(tx, fn)
+}
+
+// DryRunStatements 试运行 schema 修改语句, 返回影响行数和结构差异
+func DryRunStatements(statements ...string) (*DryRunResult, error) {
+	start := time.Now()
+	result := &DryRunResult{SQL: statements}
+	diff, changes, err := withDryRunTx("", func(db sqlx.Ext) error {
+		for _, stmt := range statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("execute failed: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.SchemaDiff = diff
+	result.RowsAffected = changes
+	result.Duration = float64(time.Since(start).Milliseconds())
+	return result, nil
+}
+
+// DryRunSQL 试运行 /db/query 的语句, txID 非空时在该交互式事务的保存点中执行
+func DryRunSQL(txID, sqlStr string, page, size int, args ...any) (*DryRunResult, error) {
+	if isTxControl(sqlStr) || releasesSavepoint(sqlStr) {
+		return nil, fmt.Errorf("transaction control statements cannot be dry-run")
+	}
+	start := time.Now()
+	result := &DryRunResult{SQL: []string{sqlStr}}
+	diff, changes, err := withDryRunTx(txID, func(db sqlx.Ext) error {
+		result.Result = executeSQL(db, sqlStr, page, size, args...)
+		if result.Result.Error != "" {
+			return fmt.Errorf("%s", result.Result.Error)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.SchemaDiff = diff
+	result.RowsAffected = changes
+	if result.Result.Type == "exec" {
+		result.Result.Affected = changes
+	}
+	result.Duration = float64(time.Since(start).Milliseconds())
+	return result, nil
+}
+
+// releasesSavepoint 判断是否含有 RELEASE 语句, 它可能释放试运行所在的保存点, 使修改无法回滚
+func releasesSavepoint(sqlStr string) bool {
+	for _, stmt := range splitStatements(tokenizeSQL(sqlStr)) {
+		if sig := significantTokens(stmt); len(sig) > 0 && sig[0].Is("RELEASE") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package services
+
+import "testing"
+
+func TestDryRunSQLLeavesDataUnchanged(t *testing.T) {
+	openTestDB(t, `CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT); INSERT INTO t (a) VALUES ('x'), ('y');`)
+
+	tests := []struct {
+		sql     string
+		wantErr bool
+	}{
+		{"DELETE FROM t", false},
+		{"UPDATE t SET a = 'z'; DELETE FROM t", false},
+		{"DROP TABLE t", false},
+		{"DELETE FROM t; COMMIT", true},
+		{"DELETE FROM t; END", true},
+		{"DELETE FROM t; ROLLBACK", true},
+		{"DELETE FROM t; RELEASE " + dryRunSavepoint, true},
+		{"DELETE FROM t; ROLLBACK TO " + dryRunSavepoint, false},
+	}
+	for _, tt := range tests {
+		_, err := DryRunSQL("", tt.sql, 1, 10)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("DryRunSQL(%q) error = %v, wantErr %v", tt.sql, err, tt.wantErr)
+		}
+		if n := countRows(t, "t"); n != 2 {
+			t.Fatalf("after DryRunSQL(%q): %d rows, want 2", tt.sql, n)
+		}
+	}
+}
+
+func TestDryRunSQLReportsChanges(t *testing.T) {
+	openTestDB(t, `CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT); INSERT INTO t (a) VALUES ('x'), ('y');`)
+
+	result, err := DryRunSQL("", "DELETE FROM t", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RowsAffected != 2 {
+		t.Errorf("RowsAffected = %d, want 2", result.RowsAffected)
+	}
+	result, err = DryRunSQL("", "CREATE INDEX idx_a ON t (a)", 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SchemaDiff.Added) != 1 || result.SchemaDiff.Added[0].Name != "idx_a" {
+		t.Errorf("SchemaDiff.Added = %+v, want idx_a", result.SchemaDiff.Added)
+	}
+}
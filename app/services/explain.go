@@ -0,0 +1,188 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// PlanNode 表示 EXPLAIN QUERY PLAN 的一个节点
+type PlanNode struct {
+	ID       int         `json:"id"`
+	Parent   int         `json:"parent"`
+	Detail   string      `json:"detail"`
+	FullScan bool        `json:"fullScan,omitempty"` // 全表扫描
+	TempTree bool        `json:"tempTree,omitempty"` // 使用临时 B-tree (排序/去重/分组)
+	Children []*PlanNode `json:"children,omitempty"`
+}
+
+// BytecodeOp 表示 EXPLAIN 输出的一条字节码指令
+type BytecodeOp struct {
+	Addr    int    `json:"addr"`
+	Opcode  string `json:"opcode"`
+	P1      int    `json:"p1"`
+	P2      int    `json:"p2"`
+	P3      int    `json:"p3"`
+	P4      string `json:"p4,omitempty"`
+	P5      int    `json:"p5"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// bytecodeRow 用于映射 EXPLAIN 返回的数据
+type bytecodeRow struct {
+	Addr    int            `db:"addr"`
+	Opcode  string         `db:"opcode"`
+	P1      int            `db:"p1"`
+	P2      int            `db:"p2"`
+	P3      int            `db:"p3"`
+	P4      sql.NullString `db:"p4"`
+	P5      int            `db:"p5"`
+	Comment sql.NullString `db:"comment"`
+}
+
+// ExplainResult 查询计划分析结果
+type ExplainResult struct {
+	SQL       string        `json:"sql"`
+	Plan      []*PlanNode   `json:"plan"`
+	FullScans []string      `json:"fullScans,omitempty"` // 发生全表扫描的节点描述
+	TempTrees []string      `json:"tempTrees,omitempty"` // 使用临时 B-tree 的节点描述
+	Bytecode  []*BytecodeOp `json:"bytecode,omitempty"`
+	Duration  *float64      `json:"duration,omitempty"` // 实际执行毫秒
+	Rows      int64         `json:"rows,omitempty"`     // 实际执行返回/影响的行数
+}
+
+// queryPlanRow 用于映射 EXPLAIN QUERY PLAN 返回的数据
+type queryPlanRow struct {
+	ID      int    `db:"id"`
+	Parent  int    `db:"parent"`
+	NotUsed int    `db:"notused"`
+	Detail  string `db:"detail"`
+}
+
+// ExplainSQL 分析查询计划, 可选输出字节码以及实际执行耗时
+func ExplainSQL(sqlStr string, bytecode, run bool) (*ExplainResult, error) {
+	sqlStr = strings.TrimRight(cleanSQL(sqlStr), ";")
+	if sqlStr == "" {
+		return nil, fmt.Errorf("SQL is null")
+	}
+	result := &ExplainResult{SQL: sqlStr}
+
+	var planRows []queryPlanRow
+	if err := utils.DB.Select(&planRows, "EXPLAIN QUERY PLAN "+sqlStr); err != nil {
+		return nil, fmt.Errorf("explain query plan failed: %w", err)
+	}
+	result.Plan = buildPlanTree(planRows)
+	walkPlan(result.Plan, func(n *PlanNode) {
+		if n.FullScan {
+			result.FullScans = append(result.FullScans, n.Detail)
+		}
+		if n.TempTree {
+			result.TempTrees = append(result.TempTrees, n.Detail)
+		}
+	})
+
+	if bytecode {
+		var ops []bytecodeRow
+		if err := utils.DB.Select(&ops, "EXPLAIN "+sqlStr); err != nil {
+			return nil, fmt.Errorf("explain failed: %w", err)
+		}
+		result.Bytecode = make([]*BytecodeOp, len(ops))
+		for i, op := range ops {
+			result.Bytecode[i] = &BytecodeOp{
+				Addr:    op.Addr,
+				Opcode:  op.Opcode,
+				P1:      op.P1,
+				P2:      op.P2,
+				P3:      op.P3,
+				P4:      op.P4.String,
+				P5:      op.P5,
+				Comment: op.Comment.String,
+			}
+		}
+	}
+
+	if run {
+		duration, count, err := timeSQL(sqlStr)
+		if err != nil {
+			return nil, err
+		}
+		result.Duration = &duration
+		result.Rows = count
+	}
+	return result, nil
+}
+
+// buildPlanTree 将 id/parent 平铺结构转换为树
+func buildPlanTree(rows []queryPlanRow) []*PlanNode {
+	nodes := make(map[int]*PlanNode, len(rows))
+	var roots []*PlanNode
+	for _, r := range rows {
+		detail := strings.TrimSpace(r.Detail)
+		node := &PlanNode{
+			ID:       r.ID,
+			Parent:   r.Parent,
+			Detail:   detail,
+			FullScan: isFullScan(detail),
+			TempTree: strings.Contains(strings.ToUpper(detail), "TEMP B-TREE"),
+		}
+		nodes[r.ID] = node
+		if parent, ok := nodes[r.Parent]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
+
+// isFullScan 判断节点是否为全表扫描, 覆盖索引扫描和虚拟表不计入
+func isFullScan(detail string) bool {
+	upper := strings.ToUpper(detail)
+	if !strings.HasPrefix(upper, "SCAN ") {
+		return false
+	}
+	return !strings.Contains(upper, " USING ") && !strings.Contains(upper, "VIRTUAL TABLE")
+}
+
+func walkPlan(nodes []*PlanNode, fn func(*PlanNode)) {
+	for _, n := range nodes {
+		fn(n)
+		walkPlan(n.Children, fn)
+	}
+}
+
+// timeSQL 在事务中实际执行一次 SQL 并回滚, 返回耗时(毫秒)和行数
+func timeSQL(sqlStr string) (float64, int64, error) {
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return 0, 0, fmt.Errorf("begin transaction failed: %w", err)
+	}
+	defer tx.Rollback()
+
+	start := time.Now()
+	var count int64
+	if classifySQL(sqlStr) == "SELECT" {
+		rows, err := tx.Query(sqlStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("execute failed: %w", err)
+		}
+		for rows.Next() {
+			count++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return 0, 0, fmt.Errorf("row iteration error: %w", err)
+		}
+	} else {
+		res, err := tx.Exec(sqlStr)
+		if err != nil {
+			return 0, 0, fmt.Errorf("execute failed: %w", err)
+		}
+		count, _ = res.RowsAffected()
+	}
+	duration := float64(time.Since(start).Microseconds()) / 1000
+	return duration, count, nil
+}
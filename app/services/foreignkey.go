@@ -0,0 +1,365 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// foreignKeyPragma 用于映射 pragma_foreign_key_list 的一行, 复合外键每列一行
+type foreignKeyPragma struct {
+	Table    string         `db:"tbl"`
+	ID       int            `db:"id"`
+	Seq      int            `db:"seq"`
+	RefTable string         `db:"table"`
+	From     string         `db:"from"`
+	To       sql.NullString `db:"to"`
+	OnUpdate string         `db:"on_update"`
+	OnDelete string         `db:"on_delete"`
+	Match    string         `db:"match"`
+}
+
+// ForeignKeyViolation PRAGMA foreign_key_check 报告的一行孤儿数据
+type ForeignKeyViolation struct {
+	Table   string   `json:"table"`
+	RowID   *int64   `json:"rowid"` // WITHOUT ROWID 表为 null
+	Parent  string   `json:"parent"`
+	FKID    int      `json:"fkid"`
+	Columns []string `json:"columns"` // 外键列
+	Values  []any    `json:"values"`  // 外键列的值, 找不到对应父行
+}
+
+// ForeignKeyCheckResult 外键检查结果
+type ForeignKeyCheckResult struct {
+	Total      int                    `json:"total"`
+	Violations []*ForeignKeyViolation `json:"violations"` // 最多返回 limit 条
+}
+
+// loadForeignKeys 读取外键, table 为空时读取所有表
+func loadForeignKeys(db sqlx.Queryer, table string) ([]models.ForeignKeyInfo, error) {
+	query := `
+		SELECT m.name AS tbl, f.id, f.seq, f."table", f."from", f."to", f.on_update, f.on_delete, f."match"
+		FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f
+		WHERE m.type = 'table'`
+	var args []any
+	if table != "" {
+		query += " AND m.name = ?"
+		args = append(args, table)
+	}
+	query += " ORDER BY m.name, f.id, f.seq"
+	var rows []foreignKeyPragma
+	if err := sqlx.Select(db, &rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to load foreign keys: %w", err)
+	}
+
+	fks := []models.ForeignKeyInfo{}
+	implicit := make(map[int]bool) // 未声明被引用列的外键, 下标 -> true
+	for _, r := range rows {
+		n := len(fks)
+		if n == 0 || fks[n-1].Table != r.Table || fks[n-1].ID != r.ID {
+			fks = append(fks, models.ForeignKeyInfo{
+				ID:         r.ID,
+				Table:      r.Table,
+				Columns:    []string{},
+				RefTable:   r.RefTable,
+				RefColumns: []string{},
+				OnDelete:   r.OnDelete,
+				OnUpdate:   r.OnUpdate,
+				Match:      r.Match,
+			})
+			n++
+		}
+		fk := &fks[n-1]
+		fk.Columns = append(fk.Columns, r.From)
+		if r.To.Valid {
+			fk.RefColumns = append(fk.RefColumns, r.To.String)
+		} else {
+			implicit[n-1] = true
+		}
+	}
+	// 未声明被引用列时引用被引用表的主键
+	for i := range implicit {
+		var pk []string
+		if err := sqlx.Select(db, &pk, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", fks[i].RefTable); err != nil {
+			return nil, fmt.Errorf("failed to get primary key of %s: %w", fks[i].RefTable, err)
+		}
+		fks[i].RefColumns = pk
+	}
+	return fks, nil
+}
+
+// GetTableForeignKeys 获取表的外键
+func GetTableForeignKeys(tableName string) ([]models.ForeignKeyInfo, error) {
+	return loadForeignKeys(utils.DB, tableName)
+}
+
+// GetReferencingForeignKeys 获取其他表(包括自身)指向该表的外键
+func GetReferencingForeignKeys(tableName string) ([]models.ForeignKeyInfo, error) {
+	return referencingForeignKeys(utils.DB, tableName)
+}
+
+func referencingForeignKeys(db sqlx.Queryer, tableName string) ([]models.ForeignKeyInfo, error) {
+	all, err := loadForeignKeys(db, "")
+	if err != nil {
+		return nil, err
+	}
+	refs := []models.ForeignKeyInfo{}
+	for _, fk := range all {
+		if strings.EqualFold(fk.RefTable, tableName) {
+			refs = append(refs, fk)
+		}
+	}
+	return refs, nil
+}
+
+// ForeignKeyCheck 执行 PRAGMA foreign_key_check, 列出找不到父行的数据, table 为空时检查所有表
+func ForeignKeyCheck(table string, limit int) (*ForeignKeyCheckResult, error) {
+	query := "PRAGMA foreign_key_check"
+	if table != "" {
+		query += "(" + utils.QuoteIdentifier(table) + ")"
+	}
+	rows, err := utils.DB.Queryx(query)
+	if err != nil {
+		return nil, fmt.Errorf("foreign key check failed: %w", err)
+	}
+	result := &ForeignKeyCheckResult{Violations: []*ForeignKeyViolation{}}
+	for rows.Next() {
+		var v ForeignKeyViolation
+		var rowID sql.NullInt64
+		if err := rows.Scan(&v.Table, &rowID, &v.Parent, &v.FKID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		result.Total++
+		if len(result.Violations) >= limit {
+			continue
+		}
+		if rowID.Valid {
+			v.RowID = &rowID.Int64
+		}
+		result.Violations = append(result.Violations, &v)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// 补充外键列和对应的值
+	fkCache := make(map[string][]models.ForeignKeyInfo)
+	for _, v := range result.Violations {
+		fks, ok := fkCache[v.Table]
+		if !ok {
+			if fks, err = loadForeignKeys(utils.DB, v.Table); err != nil {
+				return nil, err
+			}
+			fkCache[v.Table] = fks
+		}
+		for _, fk := range fks {
+			if fk.ID != v.FKID {
+				continue
+			}
+			v.Columns = fk.Columns
+			v.Values = make([]any, len(fk.Columns))
+			if v.RowID == nil {
+				break
+			}
+			cols := make([]string, len(fk.Columns))
+			for i, c := range fk.Columns {
+				cols[i] = utils.QuoteIdentifier(c)
+			}
+			row := utils.DB.QueryRowx(fmt.Sprintf("SELECT %s FROM %s WHERE rowid = ?", strings.Join(cols, ", "), utils.QuoteIdentifier(v.Table)), *v.RowID)
+			dest := make([]any, len(v.Values))
+			for i := range v.Values {
+				dest[i] = &v.Values[i]
+			}
+			if err := row.Scan(dest...); err != nil {
+				return nil, fmt.Errorf("failed to load row %d of %s: %w", *v.RowID, v.Table, err)
+			}
+			for i, val := range v.Values {
+				if b, ok := val.([]byte); ok {
+					v.Values[i] = string(b)
+				}
+			}
+			break
+		}
+	}
+	return result, nil
+}
+
+// validateForeignKey 校验要添加的外键: 列存在于表中, 被引用表存在
+func validateForeignKey(db sqlx.Queryer, ddl *createTableDDL, fk *models.ForeignKey) error {
+	seen := make(map[string]bool, len(fk.Columns))
+	for _, col := range fk.Columns {
+		if ddl.Column(col) == nil {
+			return fmt.Errorf("no such column: %s", col)
+		}
+		if seen[strings.ToLower(col)] {
+			return fmt.Errorf("duplicate column: %s", col)
+		}
+		seen[strings.ToLower(col)] = true
+	}
+	if !IsValidIdentifier(fk.RefTable) {
+		return fmt.Errorf("invalid referenced table: %s", fk.RefTable)
+	}
+	var exists bool
+	if err := sqlx.Get(db, &exists, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", fk.RefTable); err != nil {
+		return fmt.Errorf("failed to check table: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("referenced table not found: %s", fk.RefTable)
+	}
+	if len(fk.RefColumns) > 0 && len(fk.RefColumns) != len(fk.Columns) {
+		return fmt.Errorf("%d columns reference %d columns", len(fk.Columns), len(fk.RefColumns))
+	}
+	for _, ref := range fk.RefColumns {
+		if !IsValidIdentifier(ref) {
+			return fmt.Errorf("invalid referenced column: %s", ref)
+		}
+	}
+	return nil
+}
+
+// AddForeignKey 通过重建表添加表级外键, 已有数据不满足约束时失败
+func AddForeignKey(table string, fk *models.ForeignKey, dryRun bool) (*RebuildResult, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	ddl, err := loadTableDDL(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateForeignKey(utils.DB, ddl, fk); err != nil {
+		return nil, fmt.Errorf("foreign key: %w", err)
+	}
+	ddl.Items = append(ddl.Items, &ddlItem{Text: renderForeignKey(fk), Constraint: true})
+	return rebuildTable(table, ddlRebuildTarget(ddl), dryRun)
+}
+
+// DropForeignKey 通过重建表删除外键, id 为 PRAGMA foreign_key_list 中的 id
+func DropForeignKey(table string, id int, dryRun bool) (*RebuildResult, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	fks, err := loadForeignKeys(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	var target *models.ForeignKeyInfo
+	for i := range fks {
+		if fks[i].ID == id {
+			target = &fks[i]
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("no such foreign key: %s #%d", table, id)
+	}
+	ddl, err := loadTableDDL(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	if !removeDDLForeignKey(ddl, target) {
+		return nil, fmt.Errorf("foreign key %s #%d not found in table definition", table, id)
+	}
+	return rebuildTable(table, ddlRebuildTarget(ddl), dryRun)
+}
+
+// removeDDLForeignKey 从建表语句中删除与 fk 对应的表级 FOREIGN KEY 约束或列上的 REFERENCES 子句
+func removeDDLForeignKey(ddl *createTableDDL, fk *models.ForeignKeyInfo) bool {
+	for i, item := range ddl.Items {
+		sig := significantTokens(tokenizeSQL(item.Text))
+		for j, t := range sig {
+			if !t.Is("REFERENCES") || j+1 >= len(sig) || !strings.EqualFold(sig[j+1].Ident(), fk.RefTable) {
+				continue
+			}
+			var columns []string
+			if item.Constraint {
+				columns = foreignKeyColumns(sig[:j])
+			} else {
+				columns = []string{item.Name}
+			}
+			if !equalFoldNames(columns, fk.Columns) {
+				continue
+			}
+			if item.Constraint {
+				ddl.Items = append(ddl.Items[:i], ddl.Items[i+1:]...)
+				return true
+			}
+			// 删除列定义中的 [CONSTRAINT name] REFERENCES ... 子句
+			start := j
+			if j >= 2 && sig[j-2].Is("CONSTRAINT") {
+				start = j - 2
+			}
+			end := foreignKeyClauseEnd(sig, j)
+			text := item.Text[:sig[start].Pos] + item.Text[sig[end].Pos+len(sig[end].Text):]
+			*item = *newDDLItem(strings.Join(strings.Fields(text), " "))
+			return true
+		}
+	}
+	return false
+}
+
+// foreignKeyColumns 返回表级约束 FOREIGN KEY (...) 中的列
+func foreignKeyColumns(sig []sqlToken) []string {
+	var columns []string
+	inside := false
+	for _, t := range sig {
+		switch {
+		case t.IsOp("("):
+			inside = true
+		case t.IsOp(")"):
+			return columns
+		case inside && t.IsIdent():
+			columns = append(columns, t.Ident())
+		}
+	}
+	return columns
+}
+
+// foreignKeyClauseEnd 返回从 REFERENCES(下标 i)开始的外键子句最后一个词法单元的下标
+func foreignKeyClauseEnd(sig []sqlToken, i int) int {
+	end := i + 1 // 被引用的表名
+	if end+1 < len(sig) && sig[end+1].IsOp("(") {
+		for end+1 < len(sig) && !sig[end].IsOp(")") {
+			end++
+		}
+	}
+	for end+1 < len(sig) {
+		next := sig[end+1]
+		switch {
+		case next.Is("ON") && end+3 < len(sig):
+			// ON DELETE|UPDATE SET NULL|SET DEFAULT|NO ACTION|CASCADE|RESTRICT
+			end += 3
+			if sig[end].Is("SET", "NO") && end+1 < len(sig) {
+				end++
+			}
+		case next.Is("MATCH") && end+2 < len(sig):
+			end += 2
+		case next.Is("NOT") && end+2 < len(sig) && sig[end+2].Is("DEFERRABLE"):
+			end += 2
+		case next.Is("DEFERRABLE"):
+			end++
+		case next.Is("INITIALLY") && end+2 < len(sig):
+			end += 2
+		default:
+			return end
+		}
+	}
+	return end
+}
+
+// equalFoldNames 忽略大小写比较两组名称
+func equalFoldNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
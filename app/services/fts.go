@@ -0,0 +1,458 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// 搜索结果片段: snippet() 先用控制字符标记匹配词, 转义 HTML 后再替换为 <mark>, 避免列内容中的标签原样输出
+const (
+	snippetOpenSentinel  = "\x02"
+	snippetCloseSentinel = "\x03"
+	snippetOpen          = "<mark>"
+	snippetClose         = "</mark>"
+	snippetEllipsis      = "…"
+	snippetTokens        = 16
+)
+
+// NewFTSIndexSchema 新建全文索引的参数
+type NewFTSIndexSchema struct {
+	Columns  []string `json:"columns" validate:"required,min=1"`
+	Tokenize string   `json:"tokenize,omitempty"` // FTS5 分词器, 如 unicode61、porter unicode61、trigram
+}
+
+// FTSIndex 表上的 FTS5 外部内容索引
+type FTSIndex struct {
+	Name         string   `json:"name"` // 虚拟表名, 为 <表名>_fts
+	Table        string   `json:"table"`
+	Columns      []string `json:"columns"`
+	Tokenize     string   `json:"tokenize,omitempty"`
+	ContentRowid string   `json:"contentRowid"`
+	Triggers     []string `json:"triggers"` // 已存在的同步触发器
+	SQL          string   `json:"sql"`
+}
+
+// SearchMatch 全文搜索中一行的排名和各列的高亮片段
+type SearchMatch struct {
+	Rank     float64           `json:"rank"`               // bm25 得分, 越小越相关
+	Snippets map[string]string `json:"snippets,omitempty"` // 列名 -> 片段, 只包含命中的列
+}
+
+// ftsName 全文索引虚拟表名
+func ftsName(table string) string {
+	return table + "_fts"
+}
+
+// ftsTriggerNames 同步触发器名: 插入、删除、更新
+func ftsTriggerNames(table string) []string {
+	name := ftsName(table)
+	return []string{name + "_ai", name + "_ad", name + "_au"}
+}
+
+// isFTSTrigger 判断触发器是否为表的全文索引同步触发器
+func isFTSTrigger(table, trigger string) bool {
+	for _, name := range ftsTriggerNames(table) {
+		if strings.EqualFold(name, trigger) {
+			return true
+		}
+	}
+	return false
+}
+
+// getFTSIndex 读取表的全文索引, 不存在时返回 nil
+func getFTSIndex(db sqlx.Queryer, table string) (*FTSIndex, error) {
+	var sqls []string
+	if err := sqlx.Select(db, &sqls, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", ftsName(table)); err != nil {
+		return nil, fmt.Errorf("failed to load full-text index: %w", err)
+	}
+	if len(sqls) == 0 {
+		return nil, nil
+	}
+	index, err := parseFTSIndex(sqls[0])
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(index.Table, table) {
+		return nil, fmt.Errorf("'%s' is not a full-text index of table %s", ftsName(table), table)
+	}
+	index.Name = ftsName(table)
+	index.Table = table
+
+	var triggers []string
+	err = sqlx.Select(db, &triggers, "SELECT name FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ? ORDER BY name", table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load triggers: %w", err)
+	}
+	index.Triggers = []string{}
+	for _, name := range triggers {
+		if isFTSTrigger(table, name) {
+			index.Triggers = append(index.Triggers, name)
+		}
+	}
+	return index, nil
+}
+
+// parseFTSIndex 解析 CREATE VIRTUAL TABLE ... USING fts5(列, content=..., content_rowid=..., tokenize=...)
+func parseFTSIndex(sqlText string) (*FTSIndex, error) {
+	tokens := tokenizeSQL(sqlText)
+	sig := significantTokens(tokens)
+	start := -1
+	for i := 0; i+2 < len(sig); i++ {
+		if sig[i].Is("USING") {
+			if sig[i+1].Is("FTS5") && sig[i+2].IsOp("(") {
+				start = i + 3
+			}
+			break
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("not an FTS5 table")
+	}
+
+	index := &FTSIndex{SQL: sqlText, ContentRowid: "rowid"}
+	var arg []sqlToken
+	addArg := func() {
+		switch {
+		case len(arg) >= 3 && arg[1].IsOp("="):
+			value := arg[2].Ident()
+			if arg[2].Kind == tokString {
+				value = strings.ReplaceAll(arg[2].Text[1:len(arg[2].Text)-1], "''", "'")
+			}
+			switch strings.ToLower(arg[0].Ident()) {
+			case "content":
+				index.Table = value
+			case "content_rowid":
+				index.ContentRowid = value
+			case "tokenize":
+				index.Tokenize = value
+			}
+		case len(arg) > 0:
+			index.Columns = append(index.Columns, arg[0].Ident())
+		}
+		arg = nil
+	}
+	depth := 1
+	for _, t := range sig[start:] {
+		switch {
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+			if depth == 0 {
+				addArg()
+				return index, nil
+			}
+		case t.IsOp(",") && depth == 1:
+			addArg()
+			continue
+		}
+		arg = append(arg, t)
+	}
+	return nil, fmt.Errorf("unbalanced parentheses in FTS5 table definition")
+}
+
+// ftsContentRowid 外部内容的 rowid 列: 单列 INTEGER 主键是 rowid 的别名, 否则用 rowid, WITHOUT ROWID 表不支持
+func ftsContentRowid(db sqlx.Queryer, table string) (string, error) {
+	ddl, err := loadTableDDL(db, table)
+	if err != nil {
+		return "", err
+	}
+	for _, opt := range ddl.OptionList() {
+		if opt == "WITHOUT ROWID" {
+			return "", fmt.Errorf("full-text index is not supported on WITHOUT ROWID table %s", table)
+		}
+	}
+	var pks []struct {
+		Name string `db:"name"`
+		Type string `db:"type"`
+	}
+	if err := sqlx.Select(db, &pks, "SELECT name, type FROM pragma_table_info(?) WHERE pk > 0", table); err != nil {
+		return "", fmt.Errorf("failed to get primary key: %w", err)
+	}
+	if len(pks) == 1 && strings.EqualFold(pks[0].Type, "INTEGER") {
+		return pks[0].Name, nil
+	}
+	return "rowid", nil
+}
+
+// CreateFTSIndexSQL 生成建全文索引的语句: 虚拟表、插入/删除/更新同步触发器和首次重建
+func CreateFTSIndexSQL(table string, index NewFTSIndexSchema) ([]string, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	if err := ensureTable(table); err != nil {
+		return nil, err
+	}
+	if existing, err := getFTSIndex(utils.DB, table); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, fmt.Errorf("table %s already has a full-text index", table)
+	}
+	if exists, err := tableExists(utils.DB, ftsName(table)); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, fmt.Errorf("table %s already exists", ftsName(table))
+	}
+	if len(index.Columns) == 0 {
+		return nil, fmt.Errorf("at least one column is required")
+	}
+
+	cols, err := GetTableColumns(table)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for _, name := range index.Columns {
+		var found bool
+		for _, col := range cols {
+			if col.Name != name || col.Hidden == 1 {
+				continue
+			}
+			found = true
+			if columnAffinity(col.Type) != "TEXT" {
+				return nil, fmt.Errorf("column %s is not a TEXT column", name)
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %s does not exist", name)
+		}
+		if seen[strings.ToLower(name)] {
+			return nil, fmt.Errorf("duplicate column: %s", name)
+		}
+		seen[strings.ToLower(name)] = true
+	}
+	rowid, err := ftsContentRowid(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	return ftsIndexStatements(table, rowid, index), nil
+}
+
+// ftsIndexStatements 生成虚拟表、同步触发器和首次重建语句, 调用方负责校验列和 rowid
+func ftsIndexStatements(table, rowid string, index NewFTSIndexSchema) []string {
+	fts := utils.QuoteIdentifier(ftsName(table))
+	args := quoteIdentifiers(index.Columns)
+	args += ", content=" + quoteLiteral(table) + ", content_rowid=" + quoteLiteral(rowid)
+	if tokenize := strings.TrimSpace(index.Tokenize); tokenize != "" {
+		args += ", tokenize=" + quoteLiteral(tokenize)
+	}
+
+	// 触发器中的 new./old. 列引用
+	values := func(ref string) string {
+		refs := make([]string, 0, len(index.Columns)+1)
+		if rowid == "rowid" {
+			refs = append(refs, ref+".rowid")
+		} else {
+			refs = append(refs, ref+"."+utils.QuoteIdentifier(rowid))
+		}
+		for _, col := range index.Columns {
+			refs = append(refs, ref+"."+utils.QuoteIdentifier(col))
+		}
+		return strings.Join(refs, ", ")
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (%s);", fts, quoteIdentifiers(index.Columns), values("new"))
+	remove := fmt.Sprintf("INSERT INTO %s (%s, rowid, %s) VALUES ('delete', %s);", fts, fts, quoteIdentifiers(index.Columns), values("old"))
+	names := ftsTriggerNames(table)
+	quotedTable := utils.QuoteIdentifier(table)
+	return []string{
+		fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s)", fts, args),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s BEGIN\n  %s\nEND", utils.QuoteIdentifier(names[0]), quotedTable, insert),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s BEGIN\n  %s\nEND", utils.QuoteIdentifier(names[1]), quotedTable, remove),
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s BEGIN\n  %s\n  %s\nEND", utils.QuoteIdentifier(names[2]), quotedTable, remove, insert),
+		ftsRebuildSQL(table),
+	}
+}
+
+// ftsRebuildSQL 按内容表重建全文索引
+func ftsRebuildSQL(table string) string {
+	fts := utils.QuoteIdentifier(ftsName(table))
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES ('rebuild')", fts, fts)
+}
+
+// CreateFTSIndex 在事务中创建全文索引和同步触发器, 并索引已有数据
+func CreateFTSIndex(table string, index NewFTSIndexSchema) (*FTSIndex, error) {
+	statements, err := CreateFTSIndexSQL(table, index)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := execStatements(tx, statements); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return getFTSIndex(utils.DB, table)
+}
+
+// GetFTSIndex 获取表的全文索引
+func GetFTSIndex(table string) (*FTSIndex, error) {
+	index, err := getFTSIndex(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	if index == nil {
+		return nil, fmt.Errorf("table %s has no full-text index", table)
+	}
+	return index, nil
+}
+
+// RebuildFTSIndex 重建全文索引, 用于绕过触发器修改数据或导入后同步
+func RebuildFTSIndex(table string) (*FTSIndex, error) {
+	index, err := GetFTSIndex(table)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := utils.DB.Exec(ftsRebuildSQL(table)); err != nil {
+		return nil, fmt.Errorf("failed to rebuild full-text index: %w", err)
+	}
+	return index, nil
+}
+
+// DropFTSIndexSQL 生成删除全文索引的语句: 先删同步触发器, 再删虚拟表
+func DropFTSIndexSQL(table string) ([]string, error) {
+	if _, err := GetFTSIndex(table); err != nil {
+		return nil, err
+	}
+	return ftsDropStatements(table), nil
+}
+
+// ftsDropStatements 删除同步触发器和虚拟表的语句
+func ftsDropStatements(table string) []string {
+	var statements []string
+	for _, name := range ftsTriggerNames(table) {
+		statements = append(statements, "DROP TRIGGER IF EXISTS "+utils.QuoteIdentifier(name))
+	}
+	return append(statements, "DROP TABLE "+utils.QuoteIdentifier(ftsName(table)))
+}
+
+// renameFTSIndexSQL 表改名时全文索引随之改名: 改名前删除旧索引, 改名后按新表名重建
+// 虚拟表的 content 和触发器都写死了表名, 无法随 ALTER TABLE 改写
+func renameFTSIndexSQL(db sqlx.Queryer, table, newName string) (before, after []string, err error) {
+	index, err := getFTSIndex(db, table)
+	if err != nil || index == nil {
+		return nil, nil, err
+	}
+	if !strings.EqualFold(table, newName) {
+		if exists, err := tableExists(db, ftsName(newName)); err != nil {
+			return nil, nil, err
+		} else if exists {
+			return nil, nil, fmt.Errorf("table %s already exists", ftsName(newName))
+		}
+	}
+	rebuilt := NewFTSIndexSchema{Columns: index.Columns, Tokenize: index.Tokenize}
+	return ftsDropStatements(table), ftsIndexStatements(newName, index.ContentRowid, rebuilt), nil
+}
+
+// DropFTSIndex 在事务中删除全文索引及其同步触发器
+func DropFTSIndex(table string) error {
+	statements, err := DropFTSIndexSQL(table)
+	if err != nil {
+		return err
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := execStatements(tx, statements); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SearchTableData 用全文索引搜索表数据, 按 bm25 排名排序, query 为 FTS5 查询语法
+func SearchTableData(tableName, query string, limit, offset int) (*QueryTableResult, error) {
+	index, err := GetFTSIndex(tableName)
+	if err != nil {
+		return nil, err
+	}
+	fts := utils.QuoteIdentifier(index.Name)
+	table := utils.QuoteIdentifier(tableName)
+	result := &QueryTableResult{
+		Data:    make([]map[string]any, 0),
+		Matches: make([]*SearchMatch, 0),
+	}
+	if err := utils.DB.Get(&result.Total, "SELECT COUNT(*) FROM "+fts+" WHERE "+fts+" MATCH ?", query); err != nil {
+		return nil, fmt.Errorf("invalid search query: %w", err)
+	}
+
+	rowid := table + ".rowid"
+	if index.ContentRowid != "rowid" {
+		rowid = table + "." + utils.QuoteIdentifier(index.ContentRowid)
+	}
+	selects := []string{table + ".*", fts + ".rank"}
+	for i := range index.Columns {
+		selects = append(selects, fmt.Sprintf("snippet(%s, %d, char(2), char(3), %s, %d)",
+			fts, i, quoteLiteral(snippetEllipsis), snippetTokens))
+	}
+	dataSQL := fmt.Sprintf("SELECT %s FROM %s JOIN %s ON %s = %s.rowid WHERE %s MATCH ? ORDER BY %s.rank LIMIT ? OFFSET ?",
+		strings.Join(selects, ", "), fts, table, rowid, fts, fts, fts)
+	rows, err := utils.DB.Queryx(dataSQL, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("search failed: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	// 表的列之后依次为 rank 和各索引列的片段
+	n := len(columns) - 1 - len(index.Columns)
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return nil, fmt.Errorf("scan failed: %w", err)
+		}
+		row := make(map[string]any, n)
+		for i := 0; i < n; i++ {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[columns[i]] = values[i]
+		}
+		match := &SearchMatch{Snippets: make(map[string]string)}
+		if rank, ok := values[n].(float64); ok {
+			match.Rank = rank
+		}
+		for i, col := range index.Columns {
+			snippet := fmt.Sprint(values[n+1+i])
+			if b, ok := values[n+1+i].([]byte); ok {
+				snippet = string(b)
+			}
+			if strings.Contains(snippet, snippetOpenSentinel) {
+				match.Snippets[col] = highlightSnippet(snippet)
+			}
+		}
+		result.Data = append(result.Data, row)
+		result.Matches = append(result.Matches, match)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fks, err := loadForeignKeys(utils.DB, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(fks) > 0 {
+		result.Links = rowLinks(fks, result.Data)
+	}
+	return result, nil
+}
+
+// highlightSnippet 转义片段中的 HTML, 再把匹配标记替换为 <mark>
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetOpenSentinel, snippetOpen)
+	return strings.ReplaceAll(escaped, snippetCloseSentinel, snippetClose)
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+func TestParseFTSIndex(t *testing.T) {
+	tests := []struct {
+		sql      string
+		table    string
+		columns  []string
+		rowid    string
+		tokenize string
+	}{
+		{
+			`CREATE VIRTUAL TABLE "notes_fts" USING fts5("title", "body", content='notes', content_rowid='id')`,
+			"notes", []string{"title", "body"}, "id", "",
+		},
+		{
+			`CREATE VIRTUAL TABLE "订单_fts" USING fts5("备注", content='订单', content_rowid='rowid', tokenize='porter unicode61')`,
+			"订单", []string{"备注"}, "rowid", "porter unicode61",
+		},
+	}
+	for _, tt := range tests {
+		index, err := parseFTSIndex(tt.sql)
+		if err != nil {
+			t.Fatalf("parseFTSIndex(%q): %v", tt.sql, err)
+		}
+		if index.Table != tt.table || !slices.Equal(index.Columns, tt.columns) || index.ContentRowid != tt.rowid || index.Tokenize != tt.tokenize {
+			t.Errorf("parseFTSIndex(%q) = %+v", tt.sql, index)
+		}
+	}
+	if _, err := parseFTSIndex("CREATE TABLE t (a)"); err == nil {
+		t.Error("parseFTSIndex accepted a plain table")
+	}
+}
+
+// ftsMatches 返回全文索引中匹配 query 的行数
+func ftsMatches(t *testing.T, table, query string) int {
+	t.Helper()
+	var n int
+	fts := utils.QuoteIdentifier(ftsName(table))
+	if err := utils.DB.Get(&n, "SELECT count(*) FROM "+fts+" WHERE "+fts+" MATCH ?", query); err != nil {
+		t.Fatalf("search %s: %v", table, err)
+	}
+	return n
+}
+
+func TestFTSIndexFollowsRenameAndDrop(t *testing.T) {
+	openTestDB(t, `CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT); INSERT INTO notes (body) VALUES ('hello world');`)
+	if _, err := CreateFTSIndex("notes", NewFTSIndexSchema{Columns: []string{"body"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RenameTable("notes", "memos"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := tableExists(utils.DB, "notes_fts"); exists {
+		t.Error("notes_fts still exists after rename")
+	}
+	if _, err := utils.DB.Exec("INSERT INTO memos (body) VALUES ('hello again')"); err != nil {
+		t.Fatal(err)
+	}
+	if n := ftsMatches(t, "memos", "hello"); n != 2 {
+		t.Errorf("memos_fts matches = %d, want 2", n)
+	}
+
+	impact, err := PreviewDropTable("memos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(impact.DroppedObjects, "table memos_fts") {
+		t.Errorf("DroppedObjects = %v, want table memos_fts", impact.DroppedObjects)
+	}
+	if err := DropSQLiteTable("memos"); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := utils.DB.Get(&left, "SELECT count(*) FROM sqlite_master WHERE name LIKE 'memos%'"); err != nil {
+		t.Fatal(err)
+	}
+	if left != 0 {
+		t.Errorf("%d objects left after dropping memos", left)
+	}
+}
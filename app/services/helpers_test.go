@@ -0,0 +1,49 @@
+package services
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// openTestDB 在临时目录中创建数据库并执行 schema, 测试期间替换 utils.DB
+func openTestDB(t *testing.T, schema string) {
+	t.Helper()
+	db, store := utils.DB, utils.Store
+	if err := utils.Connect(filepath.Join(t.TempDir(), "test.db"), false); err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	utils.Store = nil
+	t.Cleanup(func() {
+		utils.DB.Close()
+		utils.DB, utils.Store = db, store
+	})
+	if schema != "" {
+		if _, err := utils.DB.Exec(schema); err != nil {
+			t.Fatalf("schema: %v", err)
+		}
+	}
+}
+
+// countRows 返回表的行数
+func countRows(t *testing.T, table string) int {
+	t.Helper()
+	var n int
+	if err := utils.DB.Get(&n, "SELECT count(*) FROM "+utils.QuoteIdentifier(table)); err != nil {
+		t.Fatalf("count %s: %v", table, err)
+	}
+	return n
+}
+
+// openTestStore 在临时目录中创建附属存储, 需在 openTestDB 之后调用
+func openTestStore(t *testing.T) {
+	t.Helper()
+	if err := InitStore(filepath.Join(t.TempDir(), "store.db")); err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	t.Cleanup(func() {
+		utils.Store.Close()
+		utils.Store = nil
+	})
+}
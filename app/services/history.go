@@ -0,0 +1,138 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// HistoryLimit 查询历史最多保留的条数, 小于等于 0 表示不限制
+var HistoryLimit = 10000
+
+// HistoryEntry 一条查询历史
+type HistoryEntry struct {
+	ID        int64   `db:"id" json:"id"`
+	DB        string  `db:"db" json:"db"`
+	SQL       string  `db:"sql" json:"sql"`
+	Params    []any   `db:"-" json:"params,omitempty"`
+	User      string  `db:"user" json:"user"`
+	Duration  float64 `db:"duration" json:"duration"`
+	RowCount  int64   `db:"row_count" json:"rowCount"`
+	Error     string  `db:"-" json:"error,omitempty"`
+	CreatedAt string  `db:"created_at" json:"createdAt"`
+}
+
+// historyRow 用于映射 query_history 表中可为空的字段
+type historyRow struct {
+	HistoryEntry
+	ParamsJSON sql.NullString `db:"params"`
+	ErrorText  sql.NullString `db:"error"`
+}
+
+// HistoryQuery 查询历史的过滤和分页条件
+type HistoryQuery struct {
+	Keyword string // SQL 模糊搜索
+	User    string
+	DB      string
+	Page    int
+	Size    int
+}
+
+// HistoryPage 查询历史分页结果
+type HistoryPage struct {
+	Items []*HistoryEntry `json:"items"`
+	Total int64           `json:"total"`
+	Page  int             `json:"page"`
+	Size  int             `json:"size"`
+}
+
+// RecordHistory 记录一次查询执行, 失败只记日志不影响查询本身
+func RecordHistory(entry *HistoryEntry) {
+	if requireStore() != nil {
+		return
+	}
+	var params sql.NullString
+	if len(entry.Params) > 0 {
+		if b, err := json.Marshal(entry.Params); err == nil {
+			params = sql.NullString{String: string(b), Valid: true}
+		}
+	}
+	errText := sql.NullString{String: entry.Error, Valid: entry.Error != ""}
+	_, err := utils.Store.Exec(
+		`INSERT INTO query_history (db, sql, params, user, duration, row_count, error) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		utils.DBPath, entry.SQL, params, entry.User, entry.Duration, entry.RowCount, errText,
+	)
+	if err != nil {
+		utils.GetLogger("").Error("record query history failed", "error", err)
+		return
+	}
+	if HistoryLimit > 0 {
+		_, err = utils.Store.Exec(
+			`DELETE FROM query_history WHERE id <= (SELECT MAX(id) FROM query_history) - ?`,
+			HistoryLimit,
+		)
+		if err != nil {
+			utils.GetLogger("").Error("prune query history failed", "error", err)
+		}
+	}
+}
+
+// GetHistory 分页查询历史, 支持按 SQL 关键字、用户、数据库过滤
+func GetHistory(q HistoryQuery) (*HistoryPage, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	if q.Page <= 0 {
+		q.Page = 1
+	}
+	if q.Size <= 0 {
+		q.Size = 50
+	}
+
+	var where []string
+	var args []any
+	if q.Keyword != "" {
+		where = append(where, `sql LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(q.Keyword)+"%")
+	}
+	if q.User != "" {
+		where = append(where, "user = ?")
+		args = append(args, q.User)
+	}
+	if q.DB != "" {
+		where = append(where, "db = ?")
+		args = append(args, q.DB)
+	}
+	whereSQL := ""
+	if len(where) > 0 {
+		whereSQL = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	page := &HistoryPage{Items: []*HistoryEntry{}, Page: q.Page, Size: q.Size}
+	if err := utils.Store.Get(&page.Total, "SELECT COUNT(*) FROM query_history"+whereSQL, args...); err != nil {
+		return nil, fmt.Errorf("count history failed: %w", err)
+	}
+
+	var rows []historyRow
+	query := "SELECT * FROM query_history" + whereSQL + " ORDER BY id DESC LIMIT ? OFFSET ?"
+	if err := utils.Store.Select(&rows, query, append(args, q.Size, (q.Page-1)*q.Size)...); err != nil {
+		return nil, fmt.Errorf("query history failed: %w", err)
+	}
+	for _, row := range rows {
+		entry := row.HistoryEntry
+		entry.Error = row.ErrorText.String
+		if row.ParamsJSON.Valid {
+			_ = json.Unmarshal([]byte(row.ParamsJSON.String), &entry.Params)
+		}
+		page.Items = append(page.Items, &entry)
+	}
+	return page, nil
+}
+
+// escapeLike 转义 LIKE 中的通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// WITHOUT ROWID 表级联删除的最大追踪深度, 有 rowid 的表每行只展开一次, 不受此限制
+const maxCascadeDepth = 16
+
+// ImpactEntry 受影响的一张表
+type ImpactEntry struct {
+	Table       string   `json:"table"`
+	Action      string   `json:"action"`      // CASCADE / SET NULL / SET DEFAULT / RESTRICT / NO ACTION
+	Count       int64    `json:"count"`       // 受影响的行数(多条路径命中同一行只计一次)
+	ForeignKeys []string `json:"foreignKeys"` // 经由的外键, 如 posts(user_id) -> users(id)
+}
+
+// DeleteImpact 删除行或删除表前的影响预览
+type DeleteImpact struct {
+	Table              string         `json:"table"`
+	ForeignKeysEnabled bool           `json:"foreignKeysEnabled"` // 未开启时外键动作不会执行, 引用行变为孤儿
+	Rows               int64          `json:"rows"`               // 目标表中被删除的行数
+	Deleted            []*ImpactEntry `json:"deleted"`            // 级联删除
+	Nulled             []*ImpactEntry `json:"nulled"`             // SET NULL / SET DEFAULT
+	Blocked            []*ImpactEntry `json:"blocked"`            // RESTRICT / NO ACTION 或 SET NULL 到 NOT NULL 列, 删除会失败
+	Orphaned           []*ImpactEntry `json:"orphaned"`           // 未开启外键时失去父行的数据
+	Triggers           []string       `json:"triggers"`           // 会被触发的触发器
+	DroppedObjects     []string       `json:"droppedObjects,omitempty"`
+	BrokenViews        []string       `json:"brokenViews,omitempty"`
+	BrokenTriggers     []string       `json:"brokenTriggers,omitempty"` // 其他表上引用了该表的触发器
+	Truncated          bool           `json:"truncated"`                // 级联层级超过上限, 统计可能不完整
+	Blocking           bool           `json:"blocking"`                 // 操作会因外键约束失败
+}
+
+// impactBucket 同一张表、同一种动作的所有命中条件, 最后合并统计
+type impactBucket struct {
+	entry *ImpactEntry
+	conds []string
+	args  []any
+}
+
+// impactWalker 沿外键追踪删除的影响
+type impactWalker struct {
+	db       sqlx.Queryer
+	enabled  bool
+	fks      map[string][]models.ForeignKeyInfo // 被引用表(小写) -> 指向它的外键
+	buckets  map[string]*impactBucket
+	order    []string
+	impact   *DeleteImpact
+	fired    map[string]bool
+	notNulls map[string]map[string]bool
+	seen     map[string]map[int64]bool // 已追踪过级联删除的行, 表(小写) -> rowid
+	nested   map[string]bool           // WITHOUT ROWID 表按嵌套条件追踪过的外键
+}
+
+func newImpactWalker(db sqlx.Queryer, impact *DeleteImpact) (*impactWalker, error) {
+	w := &impactWalker{
+		db:       db,
+		fks:      make(map[string][]models.ForeignKeyInfo),
+		buckets:  make(map[string]*impactBucket),
+		impact:   impact,
+		fired:    make(map[string]bool),
+		notNulls: make(map[string]map[string]bool),
+		seen:     make(map[string]map[int64]bool),
+		nested:   make(map[string]bool),
+	}
+	// 读取连接实际的外键开关, 连接参数统一设置, 连接池中各连接一致
+	if err := sqlx.Get(db, &w.enabled, "PRAGMA foreign_keys"); err != nil {
+		return nil, fmt.Errorf("failed to read foreign_keys: %w", err)
+	}
+	impact.ForeignKeysEnabled = w.enabled
+	all, err := loadForeignKeys(db, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, fk := range all {
+		key := strings.ToLower(fk.RefTable)
+		w.fks[key] = append(w.fks[key], fk)
+	}
+	return w, nil
+}
+
+// describeForeignKey 外键的可读描述
+func describeForeignKey(fk models.ForeignKeyInfo) string {
+	return fmt.Sprintf("%s(%s) -> %s(%s)", fk.Table, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+}
+
+// walk 追踪 table 中满足 where 的行被删除后, 指向它们的外键引起的动作
+func (w *impactWalker) walk(table, where string, args []any, depth int) error {
+	for _, fk := range w.fks[strings.ToLower(table)] {
+		if len(fk.RefColumns) != len(fk.Columns) {
+			continue
+		}
+		if where == "1" && strings.EqualFold(fk.Table, table) {
+			continue // 删除全表时, 自引用的行本身也会被删除
+		}
+		cond := fmt.Sprintf("(%s) IN (SELECT %s FROM %s WHERE %s)",
+			quoteIdentifiers(fk.Columns), quoteIdentifiers(fk.RefColumns), utils.QuoteIdentifier(table), where)
+		var count int64
+		if err := sqlx.Get(w.db, &count, "SELECT count(*) FROM "+utils.QuoteIdentifier(fk.Table)+" WHERE "+cond, args...); err != nil {
+			return fmt.Errorf("failed to count rows of %s: %w", fk.Table, err)
+		}
+		if count == 0 {
+			continue
+		}
+
+		action := fk.OnDelete
+		switch {
+		case !w.enabled:
+			w.add("orphaned", fk, cond, args)
+		case action == "CASCADE":
+			w.add("deleted", fk, cond, args)
+			w.fire(fk.Table, "DELETE")
+			if err := w.cascade(fk, cond, args, depth); err != nil {
+				return err
+			}
+		case action == "SET NULL" || action == "SET DEFAULT":
+			if action == "SET NULL" && w.hasNotNull(fk.Table, fk.Columns) {
+				w.add("blocked", fk, cond, args)
+				continue
+			}
+			w.add("nulled", fk, cond, args)
+			w.fire(fk.Table, "UPDATE")
+		default:
+			w.add("blocked", fk, cond, args)
+		}
+	}
+	return nil
+}
+
+// cascade 继续追踪级联删除的子表行: 先取出新删除行的 rowid, 下一层按 rowid 匹配, 条件不逐层嵌套,
+// 已追踪过的行不再展开, 多条外键指向同一张表时不会成倍增长
+func (w *impactWalker) cascade(fk models.ForeignKeyInfo, cond string, args []any, depth int) error {
+	withoutRowid, err := isWithoutRowid(w.db, fk.Table)
+	if err != nil {
+		return err
+	}
+	if withoutRowid {
+		// WITHOUT ROWID 表没有 rowid, 按嵌套条件追踪, 每个外键只展开一次
+		key := describeForeignKey(fk)
+		if w.nested[key] || depth >= maxCascadeDepth {
+			w.impact.Truncated = true
+			return nil
+		}
+		w.nested[key] = true
+		return w.walk(fk.Table, cond, args, depth+1)
+	}
+
+	var rowids []int64
+	if err := sqlx.Select(w.db, &rowids, "SELECT rowid FROM "+utils.QuoteIdentifier(fk.Table)+" WHERE "+cond, args...); err != nil {
+		return fmt.Errorf("failed to load rows of %s: %w", fk.Table, err)
+	}
+	key := strings.ToLower(fk.Table)
+	if w.seen[key] == nil {
+		w.seen[key] = make(map[int64]bool)
+	}
+	fresh := rowids[:0]
+	for _, id := range rowids {
+		if !w.seen[key][id] {
+			w.seen[key][id] = true
+			fresh = append(fresh, id)
+		}
+	}
+	if len(fresh) == 0 {
+		return nil
+	}
+	ids, err := json.Marshal(fresh)
+	if err != nil {
+		return err
+	}
+	return w.walk(fk.Table, "rowid IN (SELECT value FROM json_each(?))", []any{string(ids)}, depth+1)
+}
+
+// isWithoutRowid 判断表是否为 WITHOUT ROWID 表
+func isWithoutRowid(db sqlx.Queryer, table string) (bool, error) {
+	ddl, err := loadTableDDL(db, table)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(ddl.OptionList(), "WITHOUT ROWID"), nil
+}
+
+// add 记录一条命中条件
+func (w *impactWalker) add(kind string, fk models.ForeignKeyInfo, cond string, args []any) {
+	key := kind + "\x00" + strings.ToLower(fk.Table) + "\x00" + fk.OnDelete
+	b, ok := w.buckets[key]
+	if !ok {
+		b = &impactBucket{entry: &ImpactEntry{Table: fk.Table, Action: fk.OnDelete, ForeignKeys: []string{}}}
+		w.buckets[key] = b
+		w.order = append(w.order, key)
+	}
+	desc := describeForeignKey(fk)
+	if !slices.Contains(b.entry.ForeignKeys, desc) {
+		b.entry.ForeignKeys = append(b.entry.ForeignKeys, desc)
+	}
+	b.conds = append(b.conds, cond)
+	b.args = append(b.args, args...)
+}
+
+// hasNotNull 判断外键列中是否有 NOT NULL 列
+func (w *impactWalker) hasNotNull(table string, columns []string) bool {
+	cols, ok := w.notNulls[table]
+	if !ok {
+		var names []string
+		_ = sqlx.Select(w.db, &names, `SELECT name FROM pragma_table_info(?) WHERE "notnull" = 1`, table)
+		cols = make(map[string]bool, len(names))
+		for _, name := range names {
+			cols[strings.ToLower(name)] = true
+		}
+		w.notNulls[table] = cols
+	}
+	for _, c := range columns {
+		if cols[strings.ToLower(c)] {
+			return true
+		}
+	}
+	return false
+}
+
+// fire 记录表上指定事件会触发的触发器
+func (w *impactWalker) fire(table, event string) {
+	var triggers []schemaObject
+	_ = sqlx.Select(w.db, &triggers, "SELECT type, name, tbl_name, sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ?", table)
+	for _, t := range triggers {
+		def, err := parseTrigger(t.SQL.String)
+		if err != nil {
+			continue
+		}
+		desc := fmt.Sprintf("%s (%s %s ON %s)", t.Name, def.Timing, def.Event, t.Table)
+		if def.Event == event && !w.fired[desc] {
+			w.fired[desc] = true
+			w.impact.Triggers = append(w.impact.Triggers, desc)
+		}
+	}
+}
+
+// finish 合并各表的命中条件并统计去重后的行数
+func (w *impactWalker) finish() error {
+	// 同时被级联删除的行不再计入置空
+	deleted := make(map[string]*impactBucket)
+	for _, key := range w.order {
+		if b := w.buckets[key]; strings.HasPrefix(key, "deleted\x00") {
+			deleted[strings.ToLower(b.entry.Table)] = b
+		}
+	}
+	for _, key := range w.order {
+		b := w.buckets[key]
+		where, args := "("+strings.Join(b.conds, " OR ")+")", b.args
+		if d := deleted[strings.ToLower(b.entry.Table)]; d != nil && strings.HasPrefix(key, "nulled\x00") {
+			where += " AND NOT (" + strings.Join(d.conds, " OR ") + ")"
+			args = append(append([]any{}, args...), d.args...)
+		}
+		query := fmt.Sprintf("SELECT count(*) FROM %s WHERE %s", utils.QuoteIdentifier(b.entry.Table), where)
+		if err := sqlx.Get(w.db, &b.entry.Count, query, args...); err != nil {
+			return fmt.Errorf("failed to count rows of %s: %w", b.entry.Table, err)
+		}
+		if b.entry.Count == 0 {
+			continue
+		}
+		switch strings.SplitN(key, "\x00", 2)[0] {
+		case "deleted":
+			w.impact.Deleted = append(w.impact.Deleted, b.entry)
+		case "nulled":
+			w.impact.Nulled = append(w.impact.Nulled, b.entry)
+		case "blocked":
+			w.impact.Blocked = append(w.impact.Blocked, b.entry)
+		case "orphaned":
+			w.impact.Orphaned = append(w.impact.Orphaned, b.entry)
+		}
+	}
+	w.impact.Blocking = len(w.impact.Blocked) > 0
+	return nil
+}
+
+func newDeleteImpact(table string) *DeleteImpact {
+	return &DeleteImpact{
+		Table:    table,
+		Deleted:  []*ImpactEntry{},
+		Nulled:   []*ImpactEntry{},
+		Blocked:  []*ImpactEntry{},
+		Orphaned: []*ImpactEntry{},
+		Triggers: []string{},
+	}
+}
+
+// PreviewDeleteRow 预览按主键删除一行时, 外键级联删除、置空、阻止删除的数据和会触发的触发器
+func PreviewDeleteRow(table string, key map[string]string) (*DeleteImpact, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	where, args, err := rowKeyWhere(utils.DB, table, key)
+	if err != nil {
+		return nil, err
+	}
+	impact := newDeleteImpact(table)
+	if err := utils.DB.Get(&impact.Rows, "SELECT count(*) FROM "+utils.QuoteIdentifier(table)+" WHERE "+where, args...); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	if impact.Rows == 0 {
+		return nil, fmt.Errorf("row not found in %s", table)
+	}
+	w, err := newImpactWalker(utils.DB, impact)
+	if err != nil {
+		return nil, err
+	}
+	w.fire(table, "DELETE")
+	if err := w.walk(table, where, args, 0); err != nil {
+		return nil, err
+	}
+	if err := w.finish(); err != nil {
+		return nil, err
+	}
+	return impact, nil
+}
+
+// PreviewDropTable 预览删除表的影响: 开启外键时 DROP TABLE 会先隐式删除所有行(不触发触发器),
+// 同时列出随表删除的索引和触发器, 以及因此失效的视图和其他表上的触发器
+func PreviewDropTable(table string) (*DeleteImpact, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	impact := newDeleteImpact(table)
+	if err := utils.DB.Get(&impact.Rows, "SELECT count(*) FROM "+utils.QuoteIdentifier(table)); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	w, err := newImpactWalker(utils.DB, impact)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.walk(table, "1", nil, 0); err != nil {
+		return nil, err
+	}
+	if err := w.finish(); err != nil {
+		return nil, err
+	}
+
+	var objects []schemaObject
+	if err := utils.DB.Select(&objects, "SELECT type, name, tbl_name, sql FROM sqlite_master WHERE type IN ('index', 'trigger', 'view')"); err != nil {
+		return nil, fmt.Errorf("failed to load schema: %w", err)
+	}
+	for _, o := range objects {
+		switch {
+		case o.Type != "view" && strings.EqualFold(o.Table, table):
+			impact.DroppedObjects = append(impact.DroppedObjects, o.Type+" "+o.Name)
+		case o.Type == "view" && referencesName(o.SQL.String, table):
+			impact.BrokenViews = append(impact.BrokenViews, o.Name)
+		case o.Type == "trigger" && referencesName(o.SQL.String, table):
+			impact.BrokenTriggers = append(impact.BrokenTriggers, o.Name)
+		}
+	}
+	// 全文索引虚拟表不随表自动删除, DROP TABLE 时一并删除
+	if index, err := getFTSIndex(utils.DB, table); err != nil {
+		return nil, err
+	} else if index != nil {
+		impact.DroppedObjects = append(impact.DroppedObjects, "table "+index.Name)
+	}
+	return impact, nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+const impactSchema = `
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id) ON DELETE CASCADE);
+CREATE TABLE comments (id INTEGER PRIMARY KEY, post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE);
+INSERT INTO users (id, name) VALUES (1, 'a'), (2, 'b');
+INSERT INTO posts (id, user_id) VALUES (1, 1), (2, 1), (3, 2);
+INSERT INTO comments (post_id) VALUES (1), (1), (2), (3);
+`
+
+func TestPreviewDeleteRowForeignKeyState(t *testing.T) {
+	tests := []struct {
+		foreignKeys bool
+		deleted     map[string]int64
+		orphaned    map[string]int64
+	}{
+		{true, map[string]int64{"posts": 2, "comments": 3}, map[string]int64{}},
+		{false, map[string]int64{}, map[string]int64{"posts": 2}},
+	}
+	for _, tt := range tests {
+		saved := utils.ForeignKeys
+		utils.ForeignKeys = tt.foreignKeys
+		openTestDB(t, impactSchema)
+		utils.ForeignKeys = saved
+
+		impact, err := PreviewDeleteRow("users", map[string]string{"id": "1"})
+		if err != nil {
+			t.Fatalf("foreignKeys=%v: %v", tt.foreignKeys, err)
+		}
+		if impact.ForeignKeysEnabled != tt.foreignKeys {
+			t.Errorf("ForeignKeysEnabled = %v, want %v", impact.ForeignKeysEnabled, tt.foreignKeys)
+		}
+		check := func(kind string, entries []*ImpactEntry, want map[string]int64) {
+			got := make(map[string]int64)
+			for _, e := range entries {
+				got[e.Table] = e.Count
+			}
+			if len(got) != len(want) {
+				t.Errorf("foreignKeys=%v %s = %v, want %v", tt.foreignKeys, kind, got, want)
+				return
+			}
+			for table, n := range want {
+				if got[table] != n {
+					t.Errorf("foreignKeys=%v %s = %v, want %v", tt.foreignKeys, kind, got, want)
+				}
+			}
+		}
+		check("deleted", impact.Deleted, tt.deleted)
+		check("orphaned", impact.Orphaned, tt.orphaned)
+	}
+}
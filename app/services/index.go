@@ -0,0 +1,165 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// IndexColumnSchema 新建索引时的一项: 列或表达式
+type IndexColumnSchema struct {
+	Column  string `json:"column,omitempty"`
+	Expr    string `json:"expr,omitempty"`    // 表达式索引, 如 lower(email)
+	Order   string `json:"order,omitempty"`   // ASC / DESC
+	Collate string `json:"collate,omitempty"` // 排序规则, 如 NOCASE
+}
+
+// render 生成索引项的 SQL
+func (c IndexColumnSchema) render() (string, error) {
+	var def string
+	switch {
+	case c.Column != "" && c.Expr != "":
+		return "", fmt.Errorf("column and expr cannot both be set")
+	case c.Column != "":
+		if !IsValidIdentifier(c.Column) {
+			return "", fmt.Errorf("invalid column name: %s", c.Column)
+		}
+		def = utils.QuoteIdentifier(c.Column)
+	case strings.TrimSpace(c.Expr) != "":
+		if err := validateExpr(c.Expr); err != nil {
+			return "", fmt.Errorf("invalid index expression: %w", err)
+		}
+		def = strings.TrimSpace(c.Expr)
+	default:
+		return "", fmt.Errorf("index item requires a column or an expr")
+	}
+	if c.Collate != "" {
+		if !IsValidIdentifier(c.Collate) {
+			return "", fmt.Errorf("invalid collation: %s", c.Collate)
+		}
+		def += " COLLATE " + c.Collate
+	}
+	switch order := strings.ToUpper(c.Order); order {
+	case "":
+	case "ASC", "DESC":
+		def += " " + order
+	default:
+		return "", fmt.Errorf("invalid order: %s", c.Order)
+	}
+	return def, nil
+}
+
+// indexItemDDL CREATE INDEX 括号内的一项
+type indexItemDDL struct {
+	Expr    string // 去掉排序和排序规则后的文本
+	Collate string
+	Desc    bool
+}
+
+// parseCreateIndex 拆分 CREATE INDEX 语句的索引项和 WHERE 条件
+func parseCreateIndex(sqlText string) ([]indexItemDDL, string, error) {
+	tokens := tokenizeSQL(sqlText)
+	open := -1
+	for i, t := range tokens {
+		if t.IsOp("(") {
+			open = i
+			break
+		}
+	}
+	if open < 0 {
+		return nil, "", fmt.Errorf("invalid CREATE INDEX statement")
+	}
+
+	var items []indexItemDDL
+	depth := 0
+	itemStart := tokens[open].Pos + 1
+	for i := open; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+			if depth == 0 {
+				items = append(items, parseIndexItem(sqlText[itemStart:t.Pos]))
+				var where string
+				rest := significantTokens(tokens[i+1:])
+				if len(rest) > 0 && rest[0].Is("WHERE") {
+					where = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(sqlText[rest[0].Pos+len(rest[0].Text):]), ";"))
+				}
+				return items, where, nil
+			}
+		case t.IsOp(",") && depth == 1:
+			items = append(items, parseIndexItem(sqlText[itemStart:t.Pos]))
+			itemStart = t.Pos + 1
+		}
+	}
+	return nil, "", fmt.Errorf("unbalanced parentheses in CREATE INDEX statement")
+}
+
+// parseIndexItem 去掉索引项结尾的 ASC/DESC 和 COLLATE
+func parseIndexItem(text string) indexItemDDL {
+	text = strings.TrimSpace(text)
+	sig := significantTokens(tokenizeSQL(text))
+	var item indexItemDDL
+	n := len(sig)
+	if n > 1 && sig[n-1].Is("ASC", "DESC") {
+		item.Desc = sig[n-1].Is("DESC")
+		n--
+	}
+	if n > 2 && sig[n-2].Is("COLLATE") {
+		item.Collate = sig[n-1].Ident()
+		n -= 2
+	}
+	if n > 0 {
+		item.Expr = strings.TrimSpace(text[:sig[n-1].Pos+len(sig[n-1].Text)])
+	}
+	return item
+}
+
+// indexXInfoPragma 用于映射 PRAGMA index_xinfo 返回的数据
+type indexXInfoPragma struct {
+	SeqNo int            `db:"seqno"`
+	Cid   int            `db:"cid"`
+	Name  sql.NullString `db:"name"` // 表达式项为 NULL
+	Desc  int            `db:"desc"`
+	Coll  string         `db:"coll"`
+	Key   int            `db:"key"` // 0 表示附加的 rowid 列
+}
+
+// getIndexItems 获取索引的各项及部分索引条件, 表达式文本从索引 SQL 中解析
+func getIndexItems(indexName, indexSQL string) ([]models.IndexColumn, string, error) {
+	var pragmas []indexXInfoPragma
+	query := `SELECT seqno, cid, name, "desc", coll, key FROM pragma_index_xinfo(?) ORDER BY seqno`
+	if err := utils.DB.Select(&pragmas, query, indexName); err != nil {
+		return nil, "", fmt.Errorf("failed to get index info: %w", err)
+	}
+	var parsed []indexItemDDL
+	var where string
+	if indexSQL != "" {
+		var err error
+		if parsed, where, err = parseCreateIndex(indexSQL); err != nil {
+			return nil, "", err
+		}
+	}
+
+	items := make([]models.IndexColumn, 0, len(pragmas))
+	for _, p := range pragmas {
+		if p.Key == 0 {
+			continue
+		}
+		item := models.IndexColumn{
+			Column:  p.Name.String,
+			Desc:    p.Desc == 1,
+			Collate: p.Coll,
+		}
+		if !p.Name.Valid && p.SeqNo < len(parsed) {
+			item.Expr = parsed[p.SeqNo].Expr
+		}
+		items = append(items, item)
+	}
+	return items, where, nil
+}
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// JSONSchema 由表结构生成的 JSON Schema (draft 2020-12), 只包含用到的关键字
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 any                    `json:"type,omitempty"` // 字符串, 可为 NULL 时为 [type, "null"]
+	Format               string                 `json:"format,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Default              any                    `json:"default,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	ReadOnly             bool                   `json:"readOnly,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	ForeignKey           *SchemaForeignKey      `json:"x-foreignKey,omitempty"` // 外键提示, 值应为父表中已存在的键
+
+	baseType string // 不含 null 的类型, 用于校验
+	nullable bool
+	loose    bool // 非 STRICT 表的亲和性类型: SQLite 会保存无法转换的值, 不校验类型
+}
+
+// SchemaForeignKey 外键列引用的父表和列
+type SchemaForeignKey struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+}
+
+// 从 VARCHAR(n) 之类的声明类型中取出长度
+var typeLengthPattern = regexp.MustCompile(`(?i)CHAR\s*\(\s*(\d+)`)
+
+// GetTableJSONSchema 根据表结构生成描述一行数据的 JSON Schema
+func GetTableJSONSchema(table string) (*JSONSchema, error) {
+	cols, ddl, err := loadTableColumns(table)
+	if err != nil {
+		return nil, err
+	}
+	if len(cols) == 0 {
+		return nil, fmt.Errorf("table '%s' not found or has no columns", table)
+	}
+	fks, err := loadForeignKeys(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	return buildJSONSchema(table, cols, ddl, fks), nil
+}
+
+// buildJSONSchema 由列信息和解析后的建表语句生成 JSON Schema: NOT NULL 且没有默认值的列为必填, CHECK (col IN (...)) 生成 enum
+// fks 只用于生成外键提示, 写入数据时校验可传 nil
+func buildJSONSchema(table string, cols []models.ColumnInfo, ddl *createTableDDL, fks []models.ForeignKeyInfo) *JSONSchema {
+	additional := false
+	schema := &JSONSchema{
+		Schema:               "https://json-schema.org/draft/2020-12/schema",
+		Title:                table,
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema),
+		Required:             []string{},
+		AdditionalProperties: &additional,
+	}
+
+	// 表级 CHECK 也可能限制单列取值
+	var tableChecks []string
+	strict := false
+	if ddl != nil {
+		strict = isStrictTable(ddl)
+		for _, item := range ddl.Items {
+			if c := item.TableConstraint(); c != nil && c.Type == "CHECK" {
+				tableChecks = append(tableChecks, c.Expr)
+			}
+		}
+	}
+
+	// 单列 INTEGER 主键是 rowid 的别名, 不传时自动分配
+	var pkCount int
+	for _, col := range cols {
+		if col.Primary {
+			pkCount++
+		}
+	}
+
+	for _, col := range cols {
+		if col.Hidden == 1 {
+			continue
+		}
+		prop := columnSchema(col, strict)
+		rowidAlias := col.Primary && pkCount == 1 && strings.EqualFold(col.Type, "INTEGER")
+		if rowidAlias {
+			// 传入 NULL 时同样自动分配
+			prop.nullable = true
+			prop.Type = []string{prop.baseType, "null"}
+			prop.Description = "assigned automatically when omitted or null"
+		}
+		for _, check := range append(append([]string{}, col.Checks...), tableChecks...) {
+			if values := checkEnum(check, col.Name); values != nil {
+				prop.Enum = values
+				if prop.nullable {
+					prop.Enum = append(prop.Enum, nil)
+				}
+				break
+			}
+		}
+		for _, fk := range fks {
+			for i, from := range fk.Columns {
+				if strings.EqualFold(from, col.Name) && i < len(fk.RefColumns) {
+					prop.ForeignKey = &SchemaForeignKey{Table: fk.RefTable, Column: fk.RefColumns[i]}
+				}
+			}
+		}
+		if col.NotNull && col.Default == "" && col.Generated == "" && !rowidAlias {
+			schema.Required = append(schema.Required, col.Name)
+		}
+		schema.Properties[col.Name] = prop
+	}
+	return schema
+}
+
+// columnSchema 按声明类型生成单列的 schema
+func columnSchema(col models.ColumnInfo, strict bool) *JSONSchema {
+	prop := &JSONSchema{}
+	kind := declaredKind(col.Type)
+	prop.loose = !strict && kind != "BOOLEAN" && kind != "DATE" && kind != "DATETIME"
+	switch kind {
+	case "INTEGER":
+		prop.baseType = "integer"
+	case "REAL", "NUMERIC":
+		prop.baseType = "number"
+	case "BOOLEAN":
+		prop.baseType = "boolean"
+	case "DATE":
+		prop.baseType, prop.Format = "string", "date"
+	case "DATETIME":
+		prop.baseType, prop.Format = "string", "date-time"
+	case "TEXT":
+		prop.baseType = "string"
+		if m := typeLengthPattern.FindStringSubmatch(col.Type); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil {
+				prop.MaxLength = &n
+			}
+		}
+	}
+	prop.nullable = !col.NotNull && !col.Primary
+	switch {
+	case prop.baseType != "" && prop.nullable:
+		prop.Type = []string{prop.baseType, "null"}
+	case prop.baseType != "":
+		prop.Type = prop.baseType
+	}
+	if col.Generated != "" {
+		prop.ReadOnly = true
+		prop.Description = "generated column: " + col.Expression
+	}
+	prop.Default = literalValue(col.Default)
+	return prop
+}
+
+// literalValue 解析默认值中的字面量, 表达式(如 CURRENT_TIMESTAMP)返回 nil
+func literalValue(text string) any {
+	sig := significantTokens(tokenizeSQL(text))
+	sign := ""
+	if len(sig) == 2 && sig[0].IsOp("+", "-") {
+		sign = sig[0].Text
+		sig = sig[1:]
+	}
+	if len(sig) != 1 {
+		return nil
+	}
+	t := sig[0]
+	switch {
+	case t.Kind == tokString:
+		return strings.ReplaceAll(t.Text[1:len(t.Text)-1], "''", "'")
+	case t.Kind == tokNumber:
+		if i, err := strconv.ParseInt(sign+t.Text, 0, 64); err == nil {
+			return i
+		}
+		if f, err := strconv.ParseFloat(sign+t.Text, 64); err == nil {
+			return f
+		}
+	case t.Is("TRUE"):
+		return true
+	case t.Is("FALSE"):
+		return false
+	}
+	return nil
+}
+
+// checkEnum 识别 column IN (literal, ...) 形式的 CHECK 表达式, 返回允许的取值
+func checkEnum(expr, column string) []any {
+	sig := significantTokens(tokenizeSQL(expr))
+	if len(sig) < 5 || !sig[0].IsIdent() || !strings.EqualFold(sig[0].Ident(), column) ||
+		!sig[1].Is("IN") || !sig[2].IsOp("(") || !sig[len(sig)-1].IsOp(")") {
+		return nil
+	}
+	// 逗号分隔的每一项都必须是字面量, 负数由符号和数字两个词法单元组成
+	var values []any
+	start := 3
+	for i := 3; i < len(sig); i++ {
+		if !sig[i].IsOp(",") && i < len(sig)-1 {
+			continue
+		}
+		if i == start {
+			return nil
+		}
+		v := literalValue(expr[sig[start].Pos : sig[i-1].Pos+len(sig[i-1].Text)])
+		if v == nil {
+			return nil
+		}
+		values = append(values, v)
+		start = i + 1
+	}
+	return values
+}
+
+// validateRow 按 JSON Schema 校验已转换过类型的一行数据, partial 为 true 时(更新)不检查必填列
+func validateRow(schema *JSONSchema, data map[string]any, partial bool) FieldErrors {
+	var errs FieldErrors
+	if !partial {
+		for _, name := range schema.Required {
+			if _, ok := data[name]; !ok {
+				errs = append(errs, &FieldError{Field: name, Message: "is required"})
+			}
+		}
+	}
+	for k, v := range data {
+		prop, ok := schema.Properties[k]
+		if !ok || prop.ReadOnly {
+			continue
+		}
+		if msg := prop.check(v); msg != "" {
+			errs = append(errs, &FieldError{Field: k, Value: v, Message: msg})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Field < errs[j].Field })
+	return errs
+}
+
+// check 校验单个值, 返回错误信息, 通过时为空
+func (s *JSONSchema) check(v any) string {
+	if v == nil {
+		if s.nullable {
+			return ""
+		}
+		return "must not be null"
+	}
+	switch s.baseType {
+	case "integer":
+		if _, ok := v.(int64); !ok && !s.loose {
+			return "must be an integer"
+		}
+	case "number":
+		switch v.(type) {
+		case int64, float64:
+		default:
+			if !s.loose {
+				return "must be a number"
+			}
+		}
+	case "boolean":
+		if i, ok := v.(int64); !ok || (i != 0 && i != 1) {
+			return "must be a boolean"
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			// 日期列也接受 Unix 时间戳
+			if _, isNumber := v.(float64); (isNumber && s.Format != "") || s.loose {
+				break
+			}
+			return "must be a string"
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(str) > *s.MaxLength {
+			return fmt.Sprintf("must be at most %d characters", *s.MaxLength)
+		}
+		if s.Format == "date" {
+			if _, err := time.Parse("2006-01-02", str); err != nil {
+				return "must be a date (YYYY-MM-DD)"
+			}
+		}
+	}
+	if s.Enum != nil && !enumContains(s.Enum, v) {
+		allowed := make([]string, 0, len(s.Enum))
+		for _, e := range s.Enum {
+			if e != nil {
+				allowed = append(allowed, fmt.Sprint(e))
+			}
+		}
+		return "must be one of: " + strings.Join(allowed, ", ")
+	}
+	return ""
+}
+
+// enumContains 比较时数字统一为 float64
+func enumContains(values []any, v any) bool {
+	for _, e := range values {
+		if fmt.Sprint(toComparable(e)) == fmt.Sprint(toComparable(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func toComparable(v any) any {
+	switch val := v.(type) {
+	case int64:
+		return float64(val)
+	case bool:
+		if val {
+			return float64(1)
+		}
+		return float64(0)
+	}
+	return v
+}
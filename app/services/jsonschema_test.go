@@ -0,0 +1,113 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetTableJSONSchema(t *testing.T) {
+	openTestDB(t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY);
+		CREATE TABLE orders (
+			id INTEGER PRIMARY KEY,
+			status TEXT NOT NULL CHECK (status IN ('new', 'paid')),
+			code VARCHAR(3),
+			qty INTEGER NOT NULL DEFAULT 1,
+			paid BOOLEAN,
+			day DATE,
+			user_id INTEGER REFERENCES users(id),
+			total REAL GENERATED ALWAYS AS (qty * 2) VIRTUAL
+		);
+	`)
+	schema, err := GetTableJSONSchema("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(schema.Required, []string{"status"}) {
+		t.Errorf("Required = %v, want [status]", schema.Required)
+	}
+	props := schema.Properties
+	if typ, ok := props["id"].Type.([]string); !ok || !slices.Equal(typ, []string{"integer", "null"}) {
+		t.Errorf("id type = %v, want [integer null]", props["id"].Type)
+	}
+	if props["status"].Type != "string" || len(props["status"].Enum) != 2 {
+		t.Errorf("status = %+v", props["status"])
+	}
+	if props["code"].MaxLength == nil || *props["code"].MaxLength != 3 {
+		t.Errorf("code maxLength = %v, want 3", props["code"].MaxLength)
+	}
+	if props["qty"].Default != int64(1) {
+		t.Errorf("qty default = %#v, want 1", props["qty"].Default)
+	}
+	if props["day"].Format != "date" {
+		t.Errorf("day format = %q, want date", props["day"].Format)
+	}
+	if fk := props["user_id"].ForeignKey; fk == nil || fk.Table != "users" || fk.Column != "id" {
+		t.Errorf("user_id foreign key = %+v", fk)
+	}
+	if !props["total"].ReadOnly {
+		t.Error("generated column total is not read-only")
+	}
+
+	tests := []struct {
+		data    map[string]any
+		partial bool
+		want    []string
+	}{
+		{map[string]any{"status": "new"}, false, nil},
+		{map[string]any{"id": nil, "status": "paid", "code": "abc", "paid": int64(1), "day": "2024-03-01"}, false, nil},
+		{map[string]any{}, false, []string{"status"}},
+		{map[string]any{}, true, nil},
+		{map[string]any{"status": "old"}, true, []string{"status"}},
+		{map[string]any{"status": nil}, true, []string{"status"}},
+		{map[string]any{"code": "订单号码"}, true, []string{"code"}},
+		{map[string]any{"paid": int64(2)}, true, []string{"paid"}},
+		{map[string]any{"day": "03/01/2024"}, true, []string{"day"}},
+		// 非 STRICT 表的亲和性列与 SQLite 一致, 保存无法转换的值
+		{map[string]any{"qty": "many"}, true, nil},
+		{map[string]any{"total": "ignored"}, true, nil},
+	}
+	for _, tt := range tests {
+		var fields []string
+		for _, e := range validateRow(schema, tt.data, tt.partial) {
+			fields = append(fields, e.Field)
+		}
+		if !slices.Equal(fields, tt.want) {
+			t.Errorf("validateRow(%v, partial=%v) fields = %v, want %v", tt.data, tt.partial, fields, tt.want)
+		}
+	}
+}
+
+func TestJSONSchemaStrictTable(t *testing.T) {
+	openTestDB(t, `CREATE TABLE s (id INTEGER PRIMARY KEY, qty INTEGER, price REAL, note TEXT) STRICT;`)
+	schema, err := GetTableJSONSchema("s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := validateRow(schema, map[string]any{"qty": "many", "price": "cheap", "note": int64(1)}, true)
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	if want := []string{"note", "price", "qty"}; !slices.Equal(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+}
+
+func TestCheckEnum(t *testing.T) {
+	tests := []struct {
+		expr, column string
+		want         []any
+	}{
+		{"status IN ('a', 'b')", "status", []any{"a", "b"}},
+		{`"Status" in (1, -2, 3.5)`, "status", []any{int64(1), int64(-2), 3.5}},
+		{"status IN ('a', lower('B'))", "status", nil},
+		{"other IN ('a')", "status", nil},
+		{"status = 'a'", "status", nil},
+	}
+	for _, tt := range tests {
+		if got := checkEnum(tt.expr, tt.column); !slices.Equal(got, tt.want) {
+			t.Errorf("checkEnum(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// RebuildResult 表重建结果, DryRun 时所有修改均已回滚
+type RebuildResult struct {
+	Table          string      `json:"table"`
+	DryRun         bool        `json:"dryRun"`
+	SQL            []string    `json:"sql"`                      // 事务内依次执行的语句
+	CopiedColumns  []string    `json:"copiedColumns"`            // 从旧表复制数据的列
+	DroppedColumns []string    `json:"droppedColumns"`           // 旧表中不再保留的列
+	DroppedIndexes []string    `json:"droppedIndexes,omitempty"` // 引用了已删除列而无法重建的索引
+	Rows           int64       `json:"rows"`                     // 复制的行数
+	SchemaDiff     *SchemaDiff `json:"schemaDiff,omitempty"`
+	Duration       float64     `json:"duration"`
+}
+
+// rebuildTarget 重建后的新表
+type rebuildTarget struct {
+	createSQL func(name string) (string, error) // 以临时表名生成建表语句
+	columns   []string                          // 新表的列, 同名列从旧表复制数据
+}
+
+// rebuildPlan 重建表所需的全部语句
+type rebuildPlan struct {
+	result     *RebuildResult
+	statements []string
+	views      []string // 引用该表的视图, 重建后需校验
+}
+
+// tableColumnNames 返回表的普通列(不含生成列和隐藏列)
+func tableColumnNames(db sqlx.Queryer, table string) ([]string, error) {
+	var cols []struct {
+		Name   string `db:"name"`
+		Hidden int    `db:"hidden"`
+	}
+	if err := sqlx.Select(db, &cols, "SELECT name, hidden FROM pragma_table_xinfo(?) ORDER BY cid", table); err != nil {
+		return nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		if c.Hidden == 0 {
+			names = append(names, c.Name)
+		}
+	}
+	return names, nil
+}
+
+// referencesName 判断 SQL 中是否以标识符形式引用了 name
+func referencesName(sqlText, name string) bool {
+	for _, t := range significantTokens(tokenizeSQL(sqlText)) {
+		if t.IsIdent() && strings.EqualFold(t.Ident(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// planRebuild 按 SQLite 文档的 12 步流程生成重建语句: 建新表、复制数据、删除旧表、改名、重建索引和触发器
+func planRebuild(db sqlx.Queryer, table string, target rebuildTarget) (*rebuildPlan, error) {
+	var exists bool
+	if err := sqlx.Get(db, &exists, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type='table' AND name=?)", table); err != nil {
+		return nil, fmt.Errorf("failed to check table: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("table not found: %s", table)
+	}
+
+	// 临时表名已被占用时加数字后缀
+	tmpName, err := uniqueObjectName(db, "_rebuild_"+table, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	createSQL, err := target.createSQL(tmpName)
+	if err != nil {
+		return nil, err
+	}
+
+	oldColumns, err := tableColumnNames(db, table)
+	if err != nil {
+		return nil, err
+	}
+	oldSet := make(map[string]string, len(oldColumns))
+	for _, c := range oldColumns {
+		oldSet[strings.ToLower(c)] = c
+	}
+
+	result := &RebuildResult{Table: table, CopiedColumns: []string{}, DroppedColumns: []string{}}
+	var targets, sources []string
+	kept := make(map[string]bool)
+	for _, col := range target.columns {
+		key := strings.ToLower(col)
+		old, ok := oldSet[key]
+		if !ok {
+			continue // 新增列使用默认值
+		}
+		kept[key] = true
+		targets = append(targets, utils.QuoteIdentifier(col))
+		sources = append(sources, utils.QuoteIdentifier(old))
+		result.CopiedColumns = append(result.CopiedColumns, col)
+	}
+	for _, c := range oldColumns {
+		if !kept[strings.ToLower(c)] {
+			result.DroppedColumns = append(result.DroppedColumns, c)
+		}
+	}
+
+	quoted := utils.QuoteIdentifier(table)
+	statements := []string{createSQL}
+	if len(targets) > 0 {
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			utils.QuoteIdentifier(tmpName), strings.Join(targets, ", "), strings.Join(sources, ", "), quoted))
+	}
+	statements = append(statements,
+		"DROP TABLE "+quoted,
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", utils.QuoteIdentifier(tmpName), quoted),
+	)
+
+	// 索引和触发器随旧表一起删除, 需要按原 SQL 重建
+	var objects []schemaObject
+	err = sqlx.Select(db, &objects, `
+		SELECT type, name, tbl_name, sql
+		FROM sqlite_master
+		WHERE tbl_name = ? AND type IN ('index', 'trigger') AND sql IS NOT NULL
+		ORDER BY type, name
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexes and triggers: %w", err)
+	}
+	for _, o := range objects {
+		if o.Type == "index" && indexUsesDroppedColumn(db, o, result.DroppedColumns) {
+			result.DroppedIndexes = append(result.DroppedIndexes, o.Name)
+			continue
+		}
+		statements = append(statements, o.SQL.String)
+	}
+	// 没有 INTEGER 主键的表复制后 rowid 会重新编号, 全文索引需按新的 rowid 重建
+	if index, err := getFTSIndex(db, table); err == nil && index != nil {
+		statements = append(statements, ftsRebuildSQL(table))
+	}
+
+	plan := &rebuildPlan{result: result, statements: statements}
+	var views []schemaObject
+	if err := sqlx.Select(db, &views, "SELECT type, name, tbl_name, sql FROM sqlite_master WHERE type = 'view'"); err != nil {
+		return nil, fmt.Errorf("failed to load views: %w", err)
+	}
+	for _, v := range views {
+		if referencesName(v.SQL.String, table) {
+			plan.views = append(plan.views, v.Name)
+		}
+	}
+	result.SQL = statements
+	return plan, nil
+}
+
+// indexUsesDroppedColumn 判断索引是否包含已删除的列
+// 表达式列在 pragma_index_info 中名称为 NULL, 因此还要检查索引 SQL 中列定义和 WHERE 条件部分的标识符
+func indexUsesDroppedColumn(db sqlx.Queryer, index schemaObject, dropped []string) bool {
+	if len(dropped) == 0 {
+		return false
+	}
+	var cols []sql.NullString
+	if err := sqlx.Select(db, &cols, "SELECT name FROM pragma_index_info(?)", index.Name); err != nil {
+		return false
+	}
+	// 跳过索引名和表名, 从列定义的括号开始
+	body := ""
+	for _, t := range tokenizeSQL(index.SQL.String) {
+		if t.IsOp("(") {
+			body = index.SQL.String[t.Pos:]
+			break
+		}
+	}
+	for _, d := range dropped {
+		for _, c := range cols {
+			if c.Valid && strings.EqualFold(c.String, d) {
+				return true
+			}
+		}
+		if referencesName(body, d) {
+			return true
+		}
+	}
+	return false
+}
+
+// RebuildTable 按目标定义重建表, 支持修改列类型、约束、默认值等 ALTER TABLE 无法完成的操作
+func RebuildTable(table string, def *models.CreateTableRequest, dryRun bool) (*RebuildResult, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	columns := make([]string, len(def.Columns))
+	for i, col := range def.Columns {
+		columns[i] = col.Name
+	}
+	return rebuildTable(table, rebuildTarget{
+		createSQL: func(name string) (string, error) {
+			newDef := *def
+			newDef.TableName = name
+			return BuildCreateTableSQL(&newDef)
+		},
+		columns: columns,
+	}, dryRun)
+}
+
+// rebuildTable 在独立连接的事务中执行重建, preview 为 true 时执行后回滚
+func rebuildTable(table string, target rebuildTarget, preview bool) (*RebuildResult, error) {
+	start := time.Now()
+	ctx := context.Background()
+	// PRAGMA foreign_keys 在事务内无效, 需要在独立连接上事务开始前设置
+	conn, err := utils.DB.Connx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	// legacy_alter_table 使改名时不改写、不校验其他对象中对该表的引用, 它们应继续指向重建后的表
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return nil, fmt.Errorf("failed to disable foreign keys: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA legacy_alter_table = ON"); err != nil {
+		return nil, fmt.Errorf("failed to enable legacy_alter_table: %w", err)
+	}
+	defer func() {
+		_, _ = conn.ExecContext(ctx, "PRAGMA legacy_alter_table = OFF")
+		// 恢复连接参数中设置的外键开关
+		if utils.ForeignKeys {
+			_, _ = conn.ExecContext(ctx, "PRAGMA foreign_keys = ON")
+		}
+	}()
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	plan, err := planRebuild(tx, table, target)
+	if err != nil {
+		return nil, err
+	}
+	result := plan.result
+	result.DryRun = preview
+	if err := checkDisabledTriggerColumns(table, result.DroppedColumns); err != nil {
+		return nil, err
+	}
+
+	apply := func(db sqlx.Ext) error {
+		if err := sqlx.Get(db, &result.Rows, "SELECT count(*) FROM "+utils.QuoteIdentifier(table)); err != nil {
+			return fmt.Errorf("failed to count rows: %w", err)
+		}
+		var seq sql.NullInt64
+		_ = sqlx.Get(db, &seq, "SELECT seq FROM sqlite_sequence WHERE name = ?", table)
+		for _, stmt := range plan.statements {
+			if _, err := db.Exec(stmt); err != nil {
+				return fmt.Errorf("failed to execute %q: %w", firstLine(stmt), err)
+			}
+		}
+		// 保留 AUTOINCREMENT 计数, 避免复用已删除行的 id
+		if seq.Valid {
+			_, _ = db.Exec("UPDATE sqlite_sequence SET seq = max(seq, ?) WHERE name = ?", seq.Int64, table)
+		}
+		return checkRebuiltTable(db, table, plan.views)
+	}
+
+	if preview {
+		diff, _, err := dryRun(tx, apply)
+		if err != nil {
+			return nil, err
+		}
+		result.SchemaDiff = diff
+	} else {
+		if err := apply(tx); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to commit: %w", err)
+		}
+	}
+	result.Duration = float64(time.Since(start).Milliseconds())
+	return result, nil
+}
+
+// checkRebuiltTable 重建后在同一事务中校验外键和引用该表的视图
+// 按文档第 10 步对整个数据库执行 foreign_key_check, 无论是否开启外键; 只有涉及该表(作为子表或父表)的违反才视为失败,
+// 其他表中原有的违反不影响重建
+func checkRebuiltTable(db sqlx.Ext, table string, views []string) error {
+	var violations []struct {
+		Table  string        `db:"table"`
+		RowID  sql.NullInt64 `db:"rowid"`
+		Parent string        `db:"parent"`
+	}
+	if err := sqlx.Select(db, &violations, `SELECT "table", rowid, parent FROM pragma_foreign_key_check`); err != nil {
+		return fmt.Errorf("foreign key check failed: %w", err)
+	}
+	var related int
+	var first string
+	for _, v := range violations {
+		if !strings.EqualFold(v.Table, table) && !strings.EqualFold(v.Parent, table) {
+			continue
+		}
+		if related == 0 {
+			first = fmt.Sprintf("%s rowid %d -> %s", v.Table, v.RowID.Int64, v.Parent)
+		}
+		related++
+	}
+	if related > 0 {
+		return fmt.Errorf("rebuild would violate foreign key constraints of %s: %d rows have no parent row (first: %s)",
+			table, related, first)
+	}
+	for _, view := range views {
+		rows, err := db.Queryx("SELECT * FROM " + utils.QuoteIdentifier(view) + " LIMIT 0")
+		if err != nil {
+			return fmt.Errorf("view %s is broken by this change: %w", view, err)
+		}
+		rows.Close()
+	}
+	return nil
+}
+
+// firstLine 返回语句首行, 用于错误信息
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i] + " ..."
+	}
+	return s
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// rebuildRequest 按列名生成重建目标, 第一列为 INTEGER 主键, 其余为 TEXT
+func rebuildRequest(table string, columns ...string) *models.CreateTableRequest {
+	req := &models.CreateTableRequest{TableName: table}
+	for i, name := range columns {
+		col := models.TableColumn{Name: name, Type: "TEXT"}
+		if i == 0 {
+			col.Type, col.Primary = "INTEGER", true
+		}
+		req.Columns = append(req.Columns, col)
+	}
+	return req
+}
+
+func TestRebuildTable(t *testing.T) {
+	openTestDB(t, `
+		CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT, b TEXT);
+		CREATE INDEX idx_t_a ON t (a);
+		CREATE TRIGGER trg_t AFTER INSERT ON t BEGIN SELECT 1; END;
+		CREATE TABLE _rebuild_t (x);
+		INSERT INTO t (a, b) VALUES ('1', 'x'), ('2', 'y');
+	`)
+
+	preview, err := RebuildTable("t", rebuildRequest("t", "id", "a", "c"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.DryRun || countRows(t, "_rebuild_t") != 0 {
+		t.Errorf("dry run changed data: %+v", preview)
+	}
+
+	result, err := RebuildTable("t", rebuildRequest("t", "id", "a", "c"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rows != 2 || len(result.DroppedColumns) != 1 || result.DroppedColumns[0] != "b" {
+		t.Errorf("result = %+v", result)
+	}
+	var names []string
+	if err := utils.DB.Select(&names, "SELECT name FROM sqlite_master WHERE tbl_name = 't' ORDER BY name"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"idx_t_a", "t", "trg_t"}; !slices.Equal(names, want) {
+		t.Errorf("objects after rebuild = %v, want %v", names, want)
+	}
+	if exists, _ := tableExists(utils.DB, "_rebuild_t"); !exists {
+		t.Error("existing _rebuild_t table was removed")
+	}
+	if n := countRows(t, "t"); n != 2 {
+		t.Errorf("rows after rebuild = %d, want 2", n)
+	}
+}
+
+func TestRebuildDropsIndexesOnDroppedColumns(t *testing.T) {
+	openTestDB(t, `
+		CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT, b TEXT);
+		CREATE INDEX idx_a ON t (a);
+		CREATE INDEX idx_b ON t (b);
+		CREATE INDEX idx_lower_b ON t (lower(b));
+		CREATE INDEX idx_a_where_b ON t (a) WHERE b IS NOT NULL;
+		CREATE INDEX b ON t (a COLLATE NOCASE);
+	`)
+	result, err := RebuildTable("t", rebuildRequest("t", "id", "a"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(result.DroppedIndexes)
+	if want := []string{"idx_a_where_b", "idx_b", "idx_lower_b"}; !slices.Equal(result.DroppedIndexes, want) {
+		t.Errorf("DroppedIndexes = %v, want %v", result.DroppedIndexes, want)
+	}
+	var indexes []string
+	if err := utils.DB.Select(&indexes, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = 't' ORDER BY name"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "idx_a"}; !slices.Equal(indexes, want) {
+		t.Errorf("indexes after rebuild = %v, want %v", indexes, want)
+	}
+}
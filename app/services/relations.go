@@ -0,0 +1,182 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// RowLink 外键单元格指向的父行, UI 可据此跳转
+type RowLink struct {
+	Table string         `json:"table"`
+	Key   map[string]any `json:"key"` // 被引用列 -> 值
+}
+
+// ChildRows 引用某一父行的子表数据, 每个外键一组
+type ChildRows struct {
+	Table      string           `json:"table"`
+	ForeignKey int              `json:"foreignKey"` // 子表中的外键 id
+	Columns    []string         `json:"columns"`    // 子表中的外键列
+	OnDelete   string           `json:"onDelete"`
+	Count      int64            `json:"count"`
+	Rows       []map[string]any `json:"rows"` // 最多 limit 行
+}
+
+// rowLinks 为每行生成 外键列 -> 父行 的映射, 外键列含 NULL 时不受约束, 不生成链接
+func rowLinks(fks []models.ForeignKeyInfo, rows []map[string]any) []map[string]*RowLink {
+	links := make([]map[string]*RowLink, len(rows))
+	for i, row := range rows {
+		links[i] = make(map[string]*RowLink)
+		for _, fk := range fks {
+			if len(fk.RefColumns) != len(fk.Columns) {
+				continue // 被引用表没有主键, 无法定位父行
+			}
+			key := make(map[string]any, len(fk.Columns))
+			for j, col := range fk.Columns {
+				if row[col] == nil {
+					key = nil
+					break
+				}
+				key[fk.RefColumns[j]] = row[col]
+			}
+			if key == nil {
+				continue
+			}
+			link := &RowLink{Table: fk.RefTable, Key: key}
+			for _, col := range fk.Columns {
+				links[i][col] = link
+			}
+		}
+	}
+	return links
+}
+
+// primaryKeyColumns 返回表的主键列, 按在主键中的顺序
+func primaryKeyColumns(db sqlx.Queryer, table string) ([]string, error) {
+	var pk []string
+	if err := sqlx.Select(db, &pk, "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk", table); err != nil {
+		return nil, fmt.Errorf("failed to get primary key: %w", err)
+	}
+	return pk, nil
+}
+
+// rowKeyWhere 根据主键(没有主键时为 rowid)生成定位一行的 WHERE 条件
+func rowKeyWhere(db sqlx.Queryer, table string, key map[string]string) (string, []any, error) {
+	pk, err := primaryKeyColumns(db, table)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(pk) == 0 {
+		pk = []string{"rowid"}
+	}
+	var where []string
+	var args []any
+	for _, col := range pk {
+		val, ok := key[col]
+		if !ok {
+			return "", nil, fmt.Errorf("primary key column '%s' must be provided", col)
+		}
+		where = append(where, utils.QuoteIdentifier(col)+" = ?")
+		args = append(args, val)
+	}
+	return strings.Join(where, " AND "), args, nil
+}
+
+// findRow 按主键读取一行, 值保持驱动返回的原始类型以便作为查询参数
+func findRow(db sqlx.Queryer, table string, key map[string]string) (map[string]any, error) {
+	where, args, err := rowKeyWhere(db, table, key)
+	if err != nil {
+		return nil, err
+	}
+	row := make(map[string]any)
+	err = db.QueryRowx("SELECT * FROM "+utils.QuoteIdentifier(table)+" WHERE "+where, args...).MapScan(row)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("row not found in %s", table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load row: %w", err)
+	}
+	return row, nil
+}
+
+// childWhere 生成子表中引用父行的条件, 父行的被引用列含 NULL 时返回 false
+func childWhere(fk models.ForeignKeyInfo, parent map[string]any) (string, []any, bool) {
+	if len(fk.RefColumns) != len(fk.Columns) {
+		return "", nil, false
+	}
+	var where []string
+	var args []any
+	for i, col := range fk.Columns {
+		val, ok := parent[fk.RefColumns[i]]
+		if !ok || val == nil {
+			return "", nil, false
+		}
+		where = append(where, utils.QuoteIdentifier(col)+" = ?")
+		args = append(args, val)
+	}
+	return strings.Join(where, " AND "), args, true
+}
+
+// textValues 将 []byte 转为字符串, 便于 JSON 输出
+func textValues(row map[string]any) map[string]any {
+	for k, v := range row {
+		if b, ok := v.([]byte); ok {
+			row[k] = string(b)
+		}
+	}
+	return row
+}
+
+// GetChildRows 列出通过外键引用指定行的子表数据, 按子表分组并统计数量
+func GetChildRows(table string, key map[string]string, limit int) ([]*ChildRows, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	parent, err := findRow(utils.DB, table, key)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := referencingForeignKeys(utils.DB, table)
+	if err != nil {
+		return nil, err
+	}
+	groups := []*ChildRows{}
+	for _, fk := range fks {
+		where, args, ok := childWhere(fk, parent)
+		if !ok {
+			continue
+		}
+		group := &ChildRows{
+			Table:      fk.Table,
+			ForeignKey: fk.ID,
+			Columns:    fk.Columns,
+			OnDelete:   fk.OnDelete,
+			Rows:       []map[string]any{},
+		}
+		if err := utils.DB.Get(&group.Count, "SELECT count(*) FROM "+utils.QuoteIdentifier(fk.Table)+" WHERE "+where, args...); err != nil {
+			return nil, fmt.Errorf("failed to count rows of %s: %w", fk.Table, err)
+		}
+		if group.Count == 0 {
+			continue
+		}
+		rows, err := utils.DB.Queryx("SELECT * FROM "+utils.QuoteIdentifier(fk.Table)+" WHERE "+where+" LIMIT ?", append(args, limit)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load rows of %s: %w", fk.Table, err)
+		}
+		for rows.Next() {
+			row := make(map[string]any)
+			if err := rows.MapScan(row); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scan failed: %w", err)
+			}
+			group.Rows = append(group.Rows, textValues(row))
+		}
+		rows.Close()
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+func init() {
+	storeSchemas = append(storeSchemas, `CREATE TABLE IF NOT EXISTS saved_query (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		title TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '[]',
+		sql TEXT NOT NULL,
+		params TEXT NOT NULL DEFAULT '[]',
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`)
+}
+
+// SavedQuery 命名保存的查询
+type SavedQuery struct {
+	ID          int64    `json:"id,omitempty"`
+	Title       string   `json:"title" validate:"required"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	SQL         string   `json:"sql" validate:"required"`
+	Params      []any    `json:"params"` // 默认绑定参数
+	CreatedAt   string   `json:"createdAt,omitempty"`
+	UpdatedAt   string   `json:"updatedAt,omitempty"`
+}
+
+// savedQueryRow 用于映射 saved_query 表
+type savedQueryRow struct {
+	ID          int64  `db:"id"`
+	Title       string `db:"title"`
+	Description string `db:"description"`
+	Tags        string `db:"tags"`
+	SQL         string `db:"sql"`
+	Params      string `db:"params"`
+	CreatedAt   string `db:"created_at"`
+	UpdatedAt   string `db:"updated_at"`
+}
+
+func (r *savedQueryRow) toModel() *SavedQuery {
+	q := &SavedQuery{
+		ID:          r.ID,
+		Title:       r.Title,
+		Description: r.Description,
+		SQL:         r.SQL,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+	_ = json.Unmarshal([]byte(r.Tags), &q.Tags)
+	_ = json.Unmarshal([]byte(r.Params), &q.Params)
+	if q.Tags == nil {
+		q.Tags = []string{}
+	}
+	if q.Params == nil {
+		q.Params = []any{}
+	}
+	return q
+}
+
+// encodeSavedQuery 将标签和参数序列化为 JSON 文本
+func encodeSavedQuery(q *SavedQuery) (tags, params string, err error) {
+	if q.Tags == nil {
+		q.Tags = []string{}
+	}
+	if q.Params == nil {
+		q.Params = []any{}
+	}
+	t, err := json.Marshal(q.Tags)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid tags: %w", err)
+	}
+	p, err := json.Marshal(q.Params)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid params: %w", err)
+	}
+	return string(t), string(p), nil
+}
+
+// ListSavedQueries 查询保存的查询, 支持按关键字(标题/描述/SQL)和标签过滤
+func ListSavedQueries(keyword, tag string) ([]*SavedQuery, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	var where []string
+	var args []any
+	if keyword != "" {
+		where = append(where, `(title LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR sql LIKE ? ESCAPE '\')`)
+		like := "%" + escapeLike(keyword) + "%"
+		args = append(args, like, like, like)
+	}
+	if tag != "" {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(tags) WHERE value = ?)")
+		args = append(args, tag)
+	}
+	query := "SELECT * FROM saved_query"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY title"
+
+	var rows []savedQueryRow
+	if err := utils.Store.Select(&rows, query, args...); err != nil {
+		return nil, fmt.Errorf("failed to list saved queries: %w", err)
+	}
+	result := make([]*SavedQuery, len(rows))
+	for i := range rows {
+		result[i] = rows[i].toModel()
+	}
+	return result, nil
+}
+
+// GetSavedQuery 按 ID 获取保存的查询
+func GetSavedQuery(id int64) (*SavedQuery, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	var row savedQueryRow
+	if err := utils.Store.Get(&row, "SELECT * FROM saved_query WHERE id = ?", id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("saved query not found: %d", id)
+		}
+		return nil, fmt.Errorf("failed to get saved query: %w", err)
+	}
+	return row.toModel(), nil
+}
+
+// CreateSavedQuery 新建保存的查询
+func CreateSavedQuery(q *SavedQuery) (*SavedQuery, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	tags, params, err := encodeSavedQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	res, err := utils.Store.Exec(
+		`INSERT INTO saved_query (title, description, tags, sql, params) VALUES (?, ?, ?, ?, ?)`,
+		q.Title, q.Description, tags, q.SQL, params,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saved query: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last insert id: %w", err)
+	}
+	return GetSavedQuery(id)
+}
+
+// UpdateSavedQuery 修改保存的查询
+func UpdateSavedQuery(id int64, q *SavedQuery) (*SavedQuery, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	tags, params, err := encodeSavedQuery(q)
+	if err != nil {
+		return nil, err
+	}
+	res, err := utils.Store.Exec(
+		`UPDATE saved_query SET title = ?, description = ?, tags = ?, sql = ?, params = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`,
+		q.Title, q.Description, tags, q.SQL, params, id,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update saved query: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("saved query not found: %d", id)
+	}
+	return GetSavedQuery(id)
+}
+
+// DeleteSavedQuery 删除保存的查询
+func DeleteSavedQuery(id int64) error {
+	if err := requireStore(); err != nil {
+		return err
+	}
+	res, err := utils.Store.Exec("DELETE FROM saved_query WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved query: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("saved query not found: %d", id)
+	}
+	return nil
+}
+
+// ExportSavedQueries 将所有保存的查询写出为 JSON 文件, 不包含 ID 和时间, 便于纳入 git 管理
+func ExportSavedQueries(w io.Writer) error {
+	queries, err := ListSavedQueries("", "")
+	if err != nil {
+		return err
+	}
+	for _, q := range queries {
+		q.ID, q.CreatedAt, q.UpdatedAt = 0, "", ""
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(queries)
+}
+
+// SavedImportResult 导入保存的查询的结果
+type SavedImportResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// ImportSavedQueries 从 JSON 文件导入保存的查询, 按标题匹配, 已存在则覆盖
+func ImportSavedQueries(r io.Reader) (*SavedImportResult, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	var queries []*SavedQuery
+	if err := json.NewDecoder(r).Decode(&queries); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	tx, err := utils.Store.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &SavedImportResult{}
+	for i, q := range queries {
+		if q.Title == "" || q.SQL == "" {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: title and sql are required", i))
+			continue
+		}
+		tags, params, err := encodeSavedQuery(q)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("item %d: %s", i, err.Error()))
+			continue
+		}
+		res, err := tx.Exec(
+			`UPDATE saved_query SET description = ?, tags = ?, sql = ?, params = ?, updated_at = CURRENT_TIMESTAMP WHERE title = ?`,
+			q.Description, tags, q.SQL, params, q.Title,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", q.Title, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			result.Updated++
+			continue
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO saved_query (title, description, tags, sql, params) VALUES (?, ?, ?, ?, ?)`,
+			q.Title, q.Description, tags, q.SQL, params,
+		); err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", q.Title, err)
+		}
+		result.Created++
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return result, nil
+}
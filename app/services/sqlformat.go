@@ -0,0 +1,261 @@
+package services
+
+import (
+	"strings"
+)
+
+// FormatOptions SQL 格式化选项
+type FormatOptions struct {
+	Indent       string `json:"indent,omitempty"`       // 缩进字符串, 默认两个空格
+	KeywordCase  string `json:"keywordCase,omitempty"`  // upper / lower / preserve, 默认 upper
+	ColumnPerRow bool   `json:"columnPerRow,omitempty"` // SELECT 列表每列一行
+}
+
+// 另起一行的子句关键字
+var clauseKeywords = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "GROUP": true, "ORDER": true, "HAVING": true,
+	"LIMIT": true, "UNION": true, "INTERSECT": true, "EXCEPT": true, "VALUES": true, "SET": true,
+	"WITH": true, "RETURNING": true, "WINDOW": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	"REPLACE": true, "BEGIN": true, "END": true,
+}
+
+// JOIN 及其前缀关键字, 整体另起一行
+var joinKeywords = map[string]bool{
+	"JOIN": true, "LEFT": true, "RIGHT": true, "FULL": true, "INNER": true, "OUTER": true,
+	"CROSS": true, "NATURAL": true,
+}
+
+// 后面紧跟对象名的关键字, 对象名后的括号不是函数调用
+var objectNameKeywords = map[string]bool{
+	"TABLE": true, "INTO": true, "ON": true, "EXISTS": true, "VIEW": true, "INDEX": true,
+	"REFERENCES": true, "USING": true,
+}
+
+var keywordSet = func() map[string]bool {
+	set := make(map[string]bool, len(sqliteKeywords))
+	for _, kw := range sqliteKeywords {
+		set[kw] = true
+	}
+	return set
+}()
+
+// parenFrame 括号层级, 子查询会缩进
+type parenFrame struct {
+	subquery   bool
+	indent     int // 进入括号前的子句缩进
+	lineIndent int // 左括号所在行的缩进, 右括号与之对齐
+	clause     string
+}
+
+// sqlFormatter 基于词法单元的简易格式化器
+type sqlFormatter struct {
+	opts    FormatOptions
+	out     strings.Builder
+	indent  int    // 当前子句的基础缩进
+	line    int    // 当前行的缩进
+	clause  string // 当前所在子句
+	stack   []parenFrame
+	between bool // BETWEEN ... AND 中的 AND 不换行
+	trigger bool // 是否为 CREATE TRIGGER 语句
+	bol     bool // 是否在行首
+	prev    sqlToken
+	prev2   sqlToken
+}
+
+// FormatSQL 格式化 SQL: 关键字大小写、子句换行、JOIN/CTE/子查询缩进, 保留注释和字面量
+func FormatSQL(sqlText string, opts FormatOptions) string {
+	if opts.Indent == "" {
+		opts.Indent = "  "
+	}
+	if opts.KeywordCase == "" {
+		opts.KeywordCase = "upper"
+	}
+	var parts []string
+	for _, stmt := range splitStatements(tokenizeSQL(sqlText)) {
+		f := &sqlFormatter{opts: opts, bol: true}
+		f.format(stmt)
+		if s := strings.TrimSpace(f.out.String()); s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+func (f *sqlFormatter) format(tokens []sqlToken) {
+	sig := significantTokens(tokens)
+	k := 0
+	for _, t := range tokens {
+		switch t.Kind {
+		case tokSpace:
+			continue
+		case tokComment:
+			f.write(t.Text, !f.bol)
+			if strings.HasPrefix(t.Text, "--") {
+				// 语句结束后的注释不缩进
+				if f.prev.IsOp(";") {
+					f.newline(f.indent)
+				} else {
+					f.newline(f.indent + 1)
+				}
+			}
+			continue
+		}
+		k++
+		var next sqlToken
+		if k < len(sig) {
+			next = sig[k]
+		}
+		f.token(t, next)
+		f.prev2, f.prev = f.prev, t
+	}
+}
+
+// newline 换行并缩进, 已在行首时只调整缩进
+func (f *sqlFormatter) newline(indent int) {
+	s := strings.TrimRight(f.out.String(), " \t")
+	f.out.Reset()
+	f.out.WriteString(s)
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		f.out.WriteString("\n")
+	}
+	f.out.WriteString(strings.Repeat(f.opts.Indent, indent))
+	f.line = indent
+	f.bol = true
+}
+
+func (f *sqlFormatter) write(text string, space bool) {
+	if space && !f.bol {
+		f.out.WriteString(" ")
+	}
+	f.out.WriteString(text)
+	f.bol = false
+}
+
+func (f *sqlFormatter) keyword(text string) string {
+	switch f.opts.KeywordCase {
+	case "lower":
+		return strings.ToLower(text)
+	case "preserve":
+		return text
+	default:
+		return strings.ToUpper(text)
+	}
+}
+
+// isKeyword 判断词法单元是否按关键字处理
+func isKeyword(t sqlToken) bool {
+	return t.Kind == tokWord && keywordSet[t.Upper()]
+}
+
+// inExpression 是否在普通括号(函数参数、列定义等)内
+func (f *sqlFormatter) inExpression() bool {
+	return len(f.stack) > 0 && !f.stack[len(f.stack)-1].subquery
+}
+
+func (f *sqlFormatter) token(t, next sqlToken) {
+	switch {
+	case isKeyword(t):
+		f.keywordToken(t, next)
+	case t.IsOp("("):
+		subquery := next.Is("SELECT", "WITH", "VALUES")
+		f.stack = append(f.stack, parenFrame{subquery: subquery, indent: f.indent, lineIndent: f.line, clause: f.clause})
+		// 函数调用的括号紧跟函数名, replace 既是关键字也是函数
+		call := f.prev.Kind == tokWord && !isKeyword(f.prev) && !objectNameKeywords[f.prev2.Upper()] || f.prev.Is("REPLACE")
+		f.write("(", !call && !f.prev.IsOp("(", "."))
+		if subquery {
+			f.indent = f.line + 1
+			f.newline(f.indent)
+		}
+	case t.IsOp(")"):
+		var frame parenFrame
+		if n := len(f.stack); n > 0 {
+			frame = f.stack[n-1]
+			f.stack = f.stack[:n-1]
+		}
+		if frame.subquery {
+			f.indent = frame.indent
+			f.clause = frame.clause
+			f.newline(frame.lineIndent)
+		}
+		f.write(")", false)
+	case t.IsOp(","):
+		f.write(",", false)
+		switch {
+		case f.inExpression():
+		case f.clause == "WITH":
+			f.newline(f.indent)
+		case f.clause == "SELECT" && f.opts.ColumnPerRow || f.clause == "SET":
+			f.newline(f.indent + 1)
+		}
+	case t.IsOp(";", "."):
+		f.write(t.Text, false)
+	default:
+		f.write(t.Text, !f.prev.IsOp("(", "."))
+	}
+}
+
+func (f *sqlFormatter) keywordToken(t, next sqlToken) {
+	upper := t.Upper()
+	text := f.keyword(t.Text)
+	if f.prev.IsOp(".") {
+		// 限定名中的关键字是列名, 保持原样
+		f.write(t.Text, false)
+		return
+	}
+	space := !f.prev.IsOp("(")
+	if f.inExpression() {
+		f.write(text, space)
+		return
+	}
+	switch {
+	case upper == "TRIGGER":
+		f.trigger = true
+	case upper == "BETWEEN":
+		f.between = true
+	case upper == "AND" && f.between:
+		f.between = false
+	case (upper == "AND" || upper == "OR") && (f.clause == "WHERE" || f.clause == "ON" || f.clause == "HAVING"):
+		f.newline(f.indent + 1)
+	case joinKeywords[upper] && !joinKeywords[f.prev.Upper()]:
+		f.clause = "JOIN"
+		f.newline(f.indent)
+	case upper == "ON" && f.clause == "JOIN":
+		f.clause = "ON"
+		f.newline(f.indent + 1)
+	case clauseKeywords[upper] && !f.continuesClause(upper):
+		f.clause = upper
+		if upper == "END" {
+			f.indent = max(f.indent-1, 0)
+		}
+		f.newline(f.indent)
+		f.write(text, false)
+		switch {
+		case upper == "BEGIN":
+			f.indent++
+		case upper == "SELECT" && f.opts.ColumnPerRow && !next.Is("DISTINCT", "ALL"):
+			f.newline(f.indent + 1)
+		}
+		return
+	}
+	f.write(text, space)
+}
+
+// continuesClause 判断关键字是否属于前一个子句, 如 INSERT OR REPLACE、触发器的 AFTER UPDATE
+func (f *sqlFormatter) continuesClause(upper string) bool {
+	switch upper {
+	case "REPLACE":
+		// INSERT OR REPLACE, 以及 replace() 函数
+		return f.prev.Is("OR") || f.clause != "" && !f.prev.IsOp(";")
+	case "UPDATE", "DELETE", "INSERT":
+		// 触发器中的 AFTER UPDATE / ON DELETE, 以及 ON CONFLICT DO UPDATE
+		return f.prev.Is("ON", "BEFORE", "AFTER", "OF", "DO", "INSTEAD")
+	case "END":
+		// CASE ... END 不换行
+		return f.clause != "BEGIN" && !f.prev.IsOp(";")
+	case "BEGIN":
+		return !f.trigger
+	case "WITH":
+		return f.prev.Is("WITHOUT")
+	}
+	return false
+}
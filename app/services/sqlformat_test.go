@@ -0,0 +1,37 @@
+package services
+
+import "testing"
+
+func TestFormatSQL(t *testing.T) {
+	tests := []struct {
+		sql  string
+		opts FormatOptions
+		want string
+	}{
+		{
+			"select u.name,count(*) from users u left join posts p on p.user_id=u.id where u.id in (1,2) group by u.name order by 2 desc limit 10",
+			FormatOptions{},
+			"SELECT u.name, count(*)\nFROM users u\nLEFT JOIN posts p\n  ON p.user_id = u.id\nWHERE u.id IN (1, 2)\nGROUP BY u.name\nORDER BY 2 DESC\nLIMIT 10",
+		},
+		{
+			"-- c\nupdate users set name='a;b' where id=1;delete from posts",
+			FormatOptions{},
+			"-- c\nUPDATE users\nSET name = 'a;b'\nWHERE id = 1;\n\nDELETE\nFROM posts",
+		},
+		{
+			"CREATE TRIGGER trg AFTER INSERT ON users BEGIN insert into posts(title) values (new.name); END",
+			FormatOptions{},
+			"CREATE TRIGGER trg AFTER INSERT ON users\nBEGIN\n  INSERT INTO posts (title)\n  VALUES (new.name);\nEND",
+		},
+		{
+			"SELECT a FROM t; -- done",
+			FormatOptions{KeywordCase: "lower"},
+			"select a\nfrom t; -- done",
+		},
+	}
+	for _, tt := range tests {
+		if got := FormatSQL(tt.sql, tt.opts); got != tt.want {
+			t.Errorf("FormatSQL(%q)\n got  %q\n want %q", tt.sql, got, tt.want)
+		}
+	}
+}
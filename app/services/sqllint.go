@@ -0,0 +1,373 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LintIssue 一条 SQL 检查结果
+type LintIssue struct {
+	Rule      string `json:"rule"`
+	Severity  string `json:"severity"` // error / warning
+	Message   string `json:"message"`
+	Statement int    `json:"statement"` // 语句序号, 从 1 开始
+	Offset    int    `json:"offset"`    // 字节偏移
+	Line      int    `json:"line"`      // 行号, 从 1 开始
+	Column    int    `json:"column"`    // 列号, 从 1 开始
+}
+
+// 可直接引用但不在 sqlite_master 中的内置表
+var builtinTables = map[string]bool{
+	"sqlite_master": true, "sqlite_schema": true, "sqlite_temp_master": true,
+	"sqlite_temp_schema": true, "sqlite_sequence": true, "sqlite_stat1": true,
+}
+
+// 每张表都隐含的列
+var implicitColumns = map[string]bool{"rowid": true, "oid": true, "_rowid_": true}
+
+// sqlLinter 检查时用到的 schema 信息
+type sqlLinter struct {
+	sqlText string
+	tables  map[string]string // 小写表名 -> 表名
+	views   map[string]bool
+	columns map[string]map[string]bool
+	issues  []*LintIssue
+}
+
+// LintSQL 检查有风险的写法: 无 WHERE 的 UPDATE/DELETE、视图中的 SELECT *、隐式交叉连接,
+// 以及引用不存在的表和列
+func LintSQL(sqlText string) ([]*LintIssue, error) {
+	l := &sqlLinter{
+		sqlText: sqlText,
+		tables:  make(map[string]string),
+		views:   make(map[string]bool),
+		columns: make(map[string]map[string]bool),
+		issues:  []*LintIssue{},
+	}
+	tables, err := GetTables()
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tables {
+		l.tables[strings.ToLower(t)] = t
+	}
+	views, err := GetViews()
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range *views {
+		l.views[strings.ToLower(v.Name)] = true
+	}
+
+	for i, stmt := range splitStatements(tokenizeSQL(sqlText)) {
+		sig := significantTokens(stmt)
+		if len(sig) == 0 {
+			continue
+		}
+		l.lintStatement(i+1, sig)
+	}
+	return l.issues, nil
+}
+
+func (l *sqlLinter) add(stmt int, t sqlToken, rule, severity, format string, args ...any) {
+	line := strings.Count(l.sqlText[:t.Pos], "\n") + 1
+	column := t.Pos - strings.LastIndex(l.sqlText[:t.Pos], "\n")
+	l.issues = append(l.issues, &LintIssue{
+		Rule:      rule,
+		Severity:  severity,
+		Message:   fmt.Sprintf(format, args...),
+		Statement: stmt,
+		Offset:    t.Pos,
+		Line:      line,
+		Column:    column,
+	})
+}
+
+// tableColumns 获取表的列名集合, 表不存在或无法获取时返回 nil
+func (l *sqlLinter) tableColumns(table string) map[string]bool {
+	key := strings.ToLower(table)
+	if cols, ok := l.columns[key]; ok {
+		return cols
+	}
+	var cols map[string]bool
+	if info, err := GetTableColumns(table); err == nil {
+		cols = make(map[string]bool, len(info))
+		for _, c := range info {
+			cols[strings.ToLower(c.Name)] = true
+		}
+	}
+	l.columns[key] = cols
+	return cols
+}
+
+func (l *sqlLinter) lintStatement(stmt int, sig []sqlToken) {
+	first := sig[0]
+	// 触发器体内的语句引用 NEW/OLD, 不做检查
+	if first.Is("CREATE") && len(sig) > 2 && (sig[1].Is("TRIGGER") || sig[2].Is("TRIGGER")) {
+		return
+	}
+	isView := first.Is("CREATE") && len(sig) > 2 && (sig[1].Is("VIEW") || sig[2].Is("VIEW"))
+
+	// CTE 名称可以像表一样引用
+	ctes := make(map[string]bool)
+	for i := 0; i+2 < len(sig); i++ {
+		if sig[i].IsIdent() && sig[i+1].Is("AS") && (sig[i+2].IsOp("(") || sig[i+2].Is("MATERIALIZED", "NOT")) {
+			ctes[strings.ToLower(sig[i].Ident())] = true
+		}
+	}
+
+	verb, verbIndex := mainVerb(sig)
+	aliases := make(map[string]string) // 小写别名 -> 表名
+	hasWhere := false
+	depth := 0
+	clauses := []string{""} // 每层括号当前所在子句
+	for i, t := range sig {
+		switch {
+		case t.IsOp("("):
+			depth++
+			clauses = append(clauses, "")
+			continue
+		case t.IsOp(")"):
+			if depth > 0 {
+				depth--
+				clauses = clauses[:len(clauses)-1]
+			}
+			continue
+		}
+		if t.Is("SELECT", "FROM", "WHERE", "GROUP", "ORDER", "HAVING", "LIMIT", "JOIN", "ON", "USING", "SET", "VALUES", "WINDOW", "RETURNING", "UNION", "EXCEPT", "INTERSECT") {
+			clauses[depth] = t.Upper()
+		}
+		if t.Is("WHERE") && depth == 0 {
+			hasWhere = true
+		}
+
+		// SELECT *
+		if isView && t.IsOp("*") && i > 0 && (sig[i-1].Is("SELECT", "DISTINCT", "ALL") || sig[i-1].IsOp(",", ".")) {
+			l.add(stmt, t, "select-star-in-view", "warning", "SELECT * in a view freezes the column list at creation time, list columns explicitly")
+		}
+		// FROM a, b
+		if t.IsOp(",") && clauses[depth] == "FROM" {
+			l.add(stmt, t, "implicit-cross-join", "warning", "implicit cross join, use an explicit JOIN ... ON")
+		}
+
+		// 表引用
+		refersTable := t.Is("FROM", "JOIN", "INTO") || t.IsOp(",") && clauses[depth] == "FROM" ||
+			i == verbIndex && verb == "UPDATE"
+		if refersTable && i+1 < len(sig) && sig[i+1].IsIdent() {
+			l.checkTableRef(stmt, sig, i+1, ctes, aliases)
+		}
+	}
+
+	if verb == "UPDATE" || verb == "DELETE" {
+		if !hasWhere {
+			l.add(stmt, sig[verbIndex], strings.ToLower(verb)+"-without-where", "warning", "%s without WHERE affects every row", verb)
+		}
+	}
+	l.checkColumnRefs(stmt, sig, verb, aliases)
+	l.checkBareColumns(stmt, sig, aliases)
+}
+
+// mainVerb 找到语句的主操作(跳过 WITH 子句), 返回关键字和位置
+func mainVerb(sig []sqlToken) (string, int) {
+	depth := 0
+	for i, t := range sig {
+		switch {
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+		case depth == 0 && t.Is("SELECT", "INSERT", "UPDATE", "DELETE", "REPLACE", "CREATE", "DROP", "ALTER", "PRAGMA", "VALUES"):
+			return t.Upper(), i
+		}
+	}
+	return "", -1
+}
+
+// checkTableRef 检查表是否存在, 并记录别名
+func (l *sqlLinter) checkTableRef(stmt int, sig []sqlToken, i int, ctes map[string]bool, aliases map[string]string) {
+	t := sig[i]
+	if t.Kind == tokWord && keywordSet[t.Upper()] && !t.Is("REPLACE") {
+		return
+	}
+	// schema.table 或表值函数, INSERT INTO t (...) 的括号是列清单
+	if i+1 < len(sig) && (sig[i+1].IsOp(".") || sig[i+1].IsOp("(") && !sig[i-1].Is("INTO")) {
+		return
+	}
+	name := t.Ident()
+	key := strings.ToLower(name)
+	table := name
+	switch {
+	case ctes[key] || l.views[key] || builtinTables[key]:
+		table = ""
+	case l.tables[key] != "":
+		table = l.tables[key]
+	default:
+		l.add(stmt, t, "unknown-table", "error", "no such table: %s", name)
+		table = ""
+	}
+	aliases[key] = table
+	// 别名
+	j := i + 1
+	if j < len(sig) && sig[j].Is("AS") {
+		j++
+	}
+	if j < len(sig) && sig[j].IsIdent() && !(sig[j].Kind == tokWord && keywordSet[sig[j].Upper()]) {
+		aliases[strings.ToLower(sig[j].Ident())] = table
+	}
+}
+
+// checkBareColumns 检查 SELECT 列表、WHERE、GROUP BY、HAVING 和 ORDER BY 中未限定表名的列
+// 只在所有数据源都是已知表时检查: 子查询、表值函数、视图和 CTE 的列无法确定
+func (l *sqlLinter) checkBareColumns(stmt int, sig []sqlToken, aliases map[string]string) {
+	if len(aliases) == 0 {
+		return
+	}
+	for _, table := range aliases {
+		if table == "" {
+			return
+		}
+	}
+	inFrom := false
+	for i, t := range sig {
+		if t.Is("FROM", "JOIN") {
+			inFrom = true
+		} else if t.Is("WHERE", "ON", "USING", "GROUP", "ORDER", "LIMIT", "SET") {
+			inFrom = false
+		}
+		if (t.Is("FROM", "JOIN") || inFrom && t.IsOp(",")) && i+1 < len(sig) {
+			next := sig[i+1]
+			if next.IsOp("(") || i+2 < len(sig) && next.IsIdent() && sig[i+2].IsOp("(", ".") {
+				return
+			}
+		}
+	}
+
+	known := make(map[string]bool)
+	for _, table := range aliases {
+		for col := range l.tableColumns(table) {
+			known[col] = true
+		}
+	}
+	// 每个词法单元所在的子句, 括号内的子查询单独跟踪
+	clauseAt := make([]string, len(sig))
+	clauses := []string{""}
+	for i, t := range sig {
+		switch {
+		case t.IsOp("("):
+			clauses = append(clauses, "")
+		case t.IsOp(")"):
+			if len(clauses) > 1 {
+				clauses = clauses[:len(clauses)-1]
+			}
+		case t.Is("SELECT", "FROM", "WHERE", "GROUP", "ORDER", "HAVING", "LIMIT", "JOIN", "ON", "USING", "SET", "VALUES", "WINDOW", "RETURNING", "UNION", "EXCEPT", "INTERSECT"):
+			clauses[len(clauses)-1] = t.Upper()
+		}
+		clauseAt[i] = clauses[len(clauses)-1]
+	}
+
+	// 结果列别名、窗口名等, 可在 ORDER BY 中引用
+	defined := make(map[string]bool)
+	isValue := func(t sqlToken) bool {
+		return t.IsIdent() && !isKeyword(t) || t.Kind == tokNumber || t.Kind == tokString || t.IsOp(")") || t.Is("END")
+	}
+	for i, t := range sig {
+		if i == 0 || !t.IsIdent() || isKeyword(t) {
+			continue
+		}
+		if sig[i-1].Is("AS") || clauseAt[i] == "SELECT" && isValue(sig[i-1]) {
+			defined[strings.ToLower(t.Ident())] = true
+		}
+	}
+
+	for i, t := range sig {
+		switch clauseAt[i] {
+		case "SELECT", "WHERE", "GROUP", "ORDER", "HAVING":
+		default:
+			continue
+		}
+		if !t.IsIdent() || isKeyword(t) || t.Is("TRUE", "FALSE") {
+			continue
+		}
+		// 函数调用、限定名、别名定义, 以及 OVER/COLLATE 之后的名称
+		if i+1 < len(sig) && sig[i+1].IsOp("(", ".") || i > 0 && (sig[i-1].IsOp(".") || sig[i-1].Is("AS", "OVER", "COLLATE")) {
+			continue
+		}
+		key := strings.ToLower(t.Ident())
+		if known[key] || defined[key] || implicitColumns[key] || aliases[key] != "" {
+			continue
+		}
+		l.add(stmt, t, "unknown-column", "error", "no such column: %s", t.Ident())
+	}
+}
+
+// checkColumnRefs 检查 alias.column、UPDATE SET 和 INSERT 列表中的列是否存在
+func (l *sqlLinter) checkColumnRefs(stmt int, sig []sqlToken, verb string, aliases map[string]string) {
+	check := func(t sqlToken, table string) {
+		if table == "" || implicitColumns[strings.ToLower(t.Ident())] {
+			return
+		}
+		cols := l.tableColumns(table)
+		if cols != nil && !cols[strings.ToLower(t.Ident())] {
+			l.add(stmt, t, "unknown-column", "error", "no such column: %s.%s", table, t.Ident())
+		}
+	}
+
+	for i := 0; i+2 < len(sig); i++ {
+		if sig[i].IsIdent() && sig[i+1].IsOp(".") && sig[i+2].IsIdent() {
+			// schema.table.column 跳过
+			if i+4 < len(sig) && sig[i+3].IsOp(".") {
+				i += 4
+				continue
+			}
+			if table, ok := aliases[strings.ToLower(sig[i].Ident())]; ok {
+				check(sig[i+2], table)
+			}
+			i += 2
+		}
+	}
+
+	_, verbIndex := mainVerb(sig)
+	switch verb {
+	case "UPDATE":
+		if verbIndex+1 >= len(sig) {
+			return
+		}
+		table := aliases[strings.ToLower(sig[verbIndex+1].Ident())]
+		depth := 0
+		inSet := false
+		for i := verbIndex + 1; i < len(sig); i++ {
+			t := sig[i]
+			switch {
+			case t.IsOp("("):
+				depth++
+			case t.IsOp(")"):
+				depth--
+			case depth == 0 && t.Is("SET"):
+				inSet = true
+			case depth == 0 && t.Is("WHERE", "FROM", "RETURNING"):
+				inSet = false
+			case inSet && depth == 0 && t.IsIdent() && i+1 < len(sig) && sig[i+1].IsOp("=") && (sig[i-1].Is("SET") || sig[i-1].IsOp(",")):
+				check(t, table)
+			}
+		}
+	case "INSERT", "REPLACE":
+		for i := verbIndex; i+2 < len(sig); i++ {
+			if !sig[i].Is("INTO") {
+				continue
+			}
+			table := aliases[strings.ToLower(sig[i+1].Ident())]
+			j := i + 2
+			if j < len(sig) && sig[j].Is("AS") {
+				j += 2
+			}
+			if j < len(sig) && sig[j].IsOp("(") {
+				for j++; j < len(sig) && !sig[j].IsOp(")"); j++ {
+					if sig[j].IsIdent() {
+						check(sig[j], table)
+					}
+				}
+			}
+			break
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLintSQL(t *testing.T) {
+	openTestDB(t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INT, title TEXT);
+	`)
+	tests := []struct {
+		sql  string
+		want []string // rule: 消息
+	}{
+		{"SELECT name FROM users", nil},
+		{"DELETE FROM posts", []string{"delete-without-where: DELETE without WHERE affects every row"}},
+		{"UPDATE users SET name = 1", []string{"update-without-where: UPDATE without WHERE affects every row"}},
+		{"SELECT nosuch FROM users", []string{"unknown-column: no such column: nosuch"}},
+		{"SELECT u.nosuch FROM users u", []string{"unknown-column: no such column: users.nosuch"}},
+		{"SELECT * FROM nosuch", []string{"unknown-table: no such table: nosuch"}},
+		{"SELECT name AS n FROM users ORDER BY n", nil},
+		{"WITH c AS (SELECT id FROM users) SELECT id FROM c", nil},
+		{"SELECT u.name, p.title FROM users u JOIN posts p ON p.user_id = u.id WHERE title LIKE 'a%'", nil},
+		{"SELECT count(*) OVER w FROM users WINDOW w AS (ORDER BY id)", nil},
+		{"SELECT 'nosuch' FROM users WHERE name = 'x'", nil},
+	}
+	for _, tt := range tests {
+		issues, err := LintSQL(tt.sql)
+		if err != nil {
+			t.Fatalf("LintSQL(%q): %v", tt.sql, err)
+		}
+		var got []string
+		for _, issue := range issues {
+			got = append(got, issue.Rule+": "+issue.Message)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("LintSQL(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
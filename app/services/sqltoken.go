@@ -0,0 +1,279 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind SQL 词法单元类型
+type tokenKind int
+
+const (
+	tokSpace   tokenKind = iota // 空白
+	tokComment                  // -- 或 /* */ 注释
+	tokWord                     // 关键字或未加引号的标识符
+	tokQuoted                   // "ident" `ident` [ident]
+	tokString                   // 'text'
+	tokBlob                     // x'ABCD'
+	tokNumber                   // 123 1.5e3 0x1F
+	tokParam                    // ? ?1 :name @name $name
+	tokOp                       // 运算符和标点
+)
+
+// sqlToken SQL 词法单元
+type sqlToken struct {
+	Kind tokenKind
+	Text string
+	Pos  int // 在原始 SQL 中的字节偏移
+}
+
+// Upper 返回大写文本, 用于关键字比较
+func (t sqlToken) Upper() string {
+	return strings.ToUpper(t.Text)
+}
+
+// Is 判断是否为指定关键字之一
+func (t sqlToken) Is(keywords ...string) bool {
+	if t.Kind != tokWord {
+		return false
+	}
+	for _, kw := range keywords {
+		if strings.EqualFold(t.Text, kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOp 判断是否为指定符号之一
+func (t sqlToken) IsOp(ops ...string) bool {
+	if t.Kind != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.Text == op {
+			return true
+		}
+	}
+	return false
+}
+
+// IsIdent 判断是否可作为标识符(单词或加引号的标识符)
+func (t sqlToken) IsIdent() bool {
+	return t.Kind == tokWord || t.Kind == tokQuoted
+}
+
+// Ident 返回去掉引号后的标识符
+func (t sqlToken) Ident() string {
+	if t.Kind != tokQuoted || len(t.Text) < 2 {
+		return t.Text
+	}
+	inner := t.Text[1 : len(t.Text)-1]
+	switch t.Text[0] {
+	case '"':
+		return strings.ReplaceAll(inner, `""`, `"`)
+	case '`':
+		return strings.ReplaceAll(inner, "``", "`")
+	}
+	return inner
+}
+
+// 多字符运算符, 按长度优先匹配
+var multiCharOps = []string{"->>", "||", "->", "<<", ">>", "<=", ">=", "==", "!=", "<>"}
+
+// tokenizeSQL 将 SQL 拆分为词法单元, 保留空白和注释, 拼接所有 Text 即为原始 SQL
+func tokenizeSQL(sqlText string) []sqlToken {
+	var tokens []sqlToken
+	i := 0
+	n := len(sqlText)
+	emit := func(kind tokenKind, end int) {
+		tokens = append(tokens, sqlToken{Kind: kind, Text: sqlText[i:end], Pos: i})
+		i = end
+	}
+	for i < n {
+		c := sqlText[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			j := i
+			for j < n && strings.IndexByte(" \t\n\r\f", sqlText[j]) >= 0 {
+				j++
+			}
+			emit(tokSpace, j)
+		case c == '-' && i+1 < n && sqlText[i+1] == '-':
+			j := strings.IndexByte(sqlText[i:], '\n')
+			if j < 0 {
+				emit(tokComment, n)
+			} else {
+				emit(tokComment, i+j)
+			}
+		case c == '/' && i+1 < n && sqlText[i+1] == '*':
+			j := strings.Index(sqlText[i+2:], "*/")
+			if j < 0 {
+				emit(tokComment, n)
+			} else {
+				emit(tokComment, i+2+j+2)
+			}
+		case c == '\'':
+			emit(tokString, scanQuoted(sqlText, i, '\''))
+		case c == '"':
+			emit(tokQuoted, scanQuoted(sqlText, i, '"'))
+		case c == '`':
+			emit(tokQuoted, scanQuoted(sqlText, i, '`'))
+		case c == '[':
+			j := strings.IndexByte(sqlText[i:], ']')
+			if j < 0 {
+				emit(tokQuoted, n)
+			} else {
+				emit(tokQuoted, i+j+1)
+			}
+		case (c == 'x' || c == 'X') && i+1 < n && sqlText[i+1] == '\'':
+			emit(tokBlob, scanQuoted(sqlText, i+1, '\''))
+		case c >= '0' && c <= '9' || c == '.' && i+1 < n && sqlText[i+1] >= '0' && sqlText[i+1] <= '9':
+			emit(tokNumber, scanNumber(sqlText, i))
+		case c == '?':
+			j := i + 1
+			for j < n && sqlText[j] >= '0' && sqlText[j] <= '9' {
+				j++
+			}
+			emit(tokParam, j)
+		case (c == ':' || c == '@' || c == '$') && i+1 < n && isIdentRune(rune(sqlText[i+1])):
+			j := i + 1
+			for j < n {
+				r, size := utf8.DecodeRuneInString(sqlText[j:])
+				if !isIdentRune(r) {
+					break
+				}
+				j += size
+			}
+			emit(tokParam, j)
+		default:
+			r, size := utf8.DecodeRuneInString(sqlText[i:])
+			if isIdentStart(r) {
+				j := i + size
+				for j < n {
+					r, size := utf8.DecodeRuneInString(sqlText[j:])
+					if !isIdentRune(r) {
+						break
+					}
+					j += size
+				}
+				emit(tokWord, j)
+				continue
+			}
+			matched := false
+			for _, op := range multiCharOps {
+				if strings.HasPrefix(sqlText[i:], op) {
+					emit(tokOp, i+len(op))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				emit(tokOp, i+size)
+			}
+		}
+	}
+	return tokens
+}
+
+// scanQuoted 扫描引号包围的内容, 两个连续引号表示转义
+func scanQuoted(s string, start int, quote byte) int {
+	j := start + 1
+	for j < len(s) {
+		if s[j] == quote {
+			if j+1 < len(s) && s[j+1] == quote {
+				j += 2
+				continue
+			}
+			return j + 1
+		}
+		j++
+	}
+	return len(s)
+}
+
+func scanNumber(s string, start int) int {
+	j := start
+	if strings.HasPrefix(strings.ToLower(s[j:]), "0x") {
+		j += 2
+		for j < len(s) && strings.IndexByte("0123456789abcdefABCDEF_", s[j]) >= 0 {
+			j++
+		}
+		return j
+	}
+	for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.' || s[j] == '_') {
+		j++
+	}
+	if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+		k := j + 1
+		if k < len(s) && (s[k] == '+' || s[k] == '-') {
+			k++
+		}
+		if k < len(s) && s[k] >= '0' && s[k] <= '9' {
+			j = k
+			for j < len(s) && s[j] >= '0' && s[j] <= '9' {
+				j++
+			}
+		}
+	}
+	return j
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || r > unicode.MaxASCII && !unicode.IsSpace(r) && !unicode.IsPunct(r)
+}
+
+func isIdentRune(r rune) bool {
+	return isIdentStart(r) || r == '$' || unicode.IsDigit(r)
+}
+
+// significantTokens 去掉空白和注释
+func significantTokens(tokens []sqlToken) []sqlToken {
+	result := make([]sqlToken, 0, len(tokens))
+	for _, t := range tokens {
+		if t.Kind != tokSpace && t.Kind != tokComment {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// splitStatements 按分号拆分语句, CREATE TRIGGER 的 BEGIN...END 中的分号不拆分
+func splitStatements(tokens []sqlToken) [][]sqlToken {
+	var statements [][]sqlToken
+	var current []sqlToken
+	isTrigger := false
+	depth := 0 // BEGIN/CASE ... END 嵌套深度
+	words := 0
+	for _, t := range tokens {
+		current = append(current, t)
+		if t.Kind == tokSpace || t.Kind == tokComment {
+			continue
+		}
+		words++
+		if t.Kind == tokWord {
+			switch {
+			case words <= 4 && t.Is("TRIGGER"):
+				isTrigger = true
+			case isTrigger && t.Is("BEGIN", "CASE"):
+				depth++
+			case isTrigger && t.Is("END") && depth > 0:
+				depth--
+			}
+		}
+		if t.IsOp(";") && depth == 0 {
+			statements = append(statements, current)
+			current, isTrigger, words = nil, false, 0
+		}
+	}
+	switch {
+	case len(significantTokens(current)) > 0:
+		statements = append(statements, current)
+	case len(current) > 0 && len(statements) > 0:
+		// 最后一个分号之后只有注释和空白时归入上一条语句, 格式化时保留注释
+		last := len(statements) - 1
+		statements[last] = append(statements[last], current...)
+	}
+	return statements
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSQL(t *testing.T) {
+	type tok struct {
+		kind tokenKind
+		text string
+	}
+	tests := []struct {
+		sql  string
+		want []tok
+	}{
+		{
+			`SELECT a, "b c" FROM [t] WHERE x >= 1.5e3`,
+			[]tok{{tokWord, "SELECT"}, {tokWord, "a"}, {tokOp, ","}, {tokQuoted, `"b c"`}, {tokWord, "FROM"},
+				{tokQuoted, "[t]"}, {tokWord, "WHERE"}, {tokWord, "x"}, {tokOp, ">="}, {tokNumber, "1.5e3"}},
+		},
+		{
+			`'it''s' || x'00ff' -- c`,
+			[]tok{{tokString, `'it''s'`}, {tokOp, "||"}, {tokBlob, "x'00ff'"}},
+		},
+		{
+			`?1 :name @p $v ? 0x1F .5`,
+			[]tok{{tokParam, "?1"}, {tokParam, ":name"}, {tokParam, "@p"}, {tokParam, "$v"}, {tokParam, "?"},
+				{tokNumber, "0x1F"}, {tokNumber, ".5"}},
+		},
+		{
+			"订单.备注 /* x */ `q``t` != 2",
+			[]tok{{tokWord, "订单"}, {tokOp, "."}, {tokWord, "备注"}, {tokQuoted, "`q``t`"}, {tokOp, "!="}, {tokNumber, "2"}},
+		},
+	}
+	for _, tt := range tests {
+		tokens := tokenizeSQL(tt.sql)
+		var b strings.Builder
+		for _, t := range tokens {
+			b.WriteString(t.Text)
+		}
+		if b.String() != tt.sql {
+			t.Errorf("tokens of %q do not round-trip: %q", tt.sql, b.String())
+		}
+		sig := significantTokens(tokens)
+		if len(sig) != len(tt.want) {
+			t.Errorf("tokenizeSQL(%q) = %d significant tokens, want %d: %+v", tt.sql, len(sig), len(tt.want), sig)
+			continue
+		}
+		for i, w := range tt.want {
+			if sig[i].Kind != w.kind || sig[i].Text != w.text || tt.sql[sig[i].Pos:sig[i].Pos+len(w.text)] != w.text {
+				t.Errorf("tokenizeSQL(%q)[%d] = %+v, want %+v", tt.sql, i, sig[i], w)
+			}
+		}
+	}
+}
+
+func TestIdent(t *testing.T) {
+	tests := map[string]string{
+		`name`:         "name",
+		`"a""b"`:       `a"b`,
+		"`a``b`":       "a`b",
+		`[a b]`:        "a b",
+		`"订单"`:         "订单",
+		`"order-date"`: "order-date",
+	}
+	for in, want := range tests {
+		if got := significantTokens(tokenizeSQL(in))[0].Ident(); got != want {
+			t.Errorf("Ident(%s) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"SELECT 1; SELECT ';'", []string{"SELECT 1;", " SELECT ';'"}},
+		{"SELECT 1;\n-- trailing", []string{"SELECT 1;\n-- trailing"}},
+		{
+			"CREATE TRIGGER trg AFTER INSERT ON t BEGIN UPDATE t SET a = CASE WHEN 1 THEN 2 END; DELETE FROM u; END; SELECT 2",
+			[]string{"CREATE TRIGGER trg AFTER INSERT ON t BEGIN UPDATE t SET a = CASE WHEN 1 THEN 2 END; DELETE FROM u; END;", " SELECT 2"},
+		},
+		{"BEGIN; COMMIT;", []string{"BEGIN;", " COMMIT;"}},
+		{"  ", nil},
+	}
+	for _, tt := range tests {
+		statements := splitStatements(tokenizeSQL(tt.sql))
+		var got []string
+		for _, stmt := range statements {
+			var b strings.Builder
+			for _, t := range stmt {
+				b.WriteString(t.Text)
+			}
+			got = append(got, b.String())
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || len(got) != len(tt.want) {
+			t.Errorf("splitStatements(%q) = %q, want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// storeSchemas 附属存储中的表结构, 启动时依次执行
+var storeSchemas = []string{
+	`CREATE TABLE IF NOT EXISTS query_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		db TEXT NOT NULL,
+		sql TEXT NOT NULL,
+		params TEXT,
+		user TEXT NOT NULL DEFAULT '',
+		duration REAL NOT NULL DEFAULT 0,
+		row_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_query_history_user ON query_history (user, id)`,
+}
+
+// InitStore 打开附属存储并创建所需的表
+func InitStore(path string) error {
+	if err := utils.ConnectStore(path); err != nil {
+		return fmt.Errorf("failed to open store: %w", err)
+	}
+	for _, schema := range storeSchemas {
+		if _, err := utils.Store.Exec(schema); err != nil {
+			return fmt.Errorf("failed to init store schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// requireStore 检查附属存储是否可用
+func requireStore() error {
+	if utils.Store == nil {
+		return fmt.Errorf("store is not available")
+	}
+	return nil
+}
@@ -7,9 +7,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"regexp"
 	"slices"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/fuxingjun/go-sqlite-web/app/models"
 	"github.com/fuxingjun/go-sqlite-web/app/utils"
@@ -17,7 +17,7 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
-// GetTableDetail 获取表的完整结构：字段 + 索引 + 触发器
+// GetTableDetail 获取表的完整结构：字段 + 索引 + 触发器 + 外键
 func GetTableInfo(tableName string) (*models.TableInfo, error) {
 	if !IsValidIdentifier(tableName) {
 		return nil, fmt.Errorf("invalid table name: %s", tableName)
@@ -32,6 +32,9 @@ func GetTableInfo(tableName string) (*models.TableInfo, error) {
 		return nil, fmt.Errorf("table '%s' not found or has no columns", tableName)
 	}
 	detail.Columns = cols
+	if detail.Type, err = objectType(utils.DB, tableName); err != nil {
+		return nil, err
+	}
 	// 2. 获取索引信息
 	indexes, err := GetTableIndexes(tableName)
 	if err != nil {
@@ -44,6 +47,32 @@ func GetTableInfo(tableName string) (*models.TableInfo, error) {
 		return nil, fmt.Errorf("failed to get triggers: %w", err)
 	}
 	detail.Triggers = triggers
+	// 4. 获取外键和指向本表的外键
+	if detail.ForeignKeys, err = GetTableForeignKeys(tableName); err != nil {
+		return nil, err
+	}
+	if detail.ReferencedBy, err = GetReferencingForeignKeys(tableName); err != nil {
+		return nil, err
+	}
+	// 5. 解析表级约束和表选项
+	detail.Constraints = []models.Constraint{}
+	detail.Options = []string{}
+	if detail.Type == "table" {
+		var ddl string
+		if err := utils.DB.Get(&ddl, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", tableName); err != nil {
+			return nil, fmt.Errorf("failed to get table DDL: %w", err)
+		}
+		if parsed := parseTableDefinition(ddl); parsed != nil {
+			for _, item := range parsed.Items {
+				if c := item.TableConstraint(); c != nil {
+					detail.Constraints = append(detail.Constraints, *c)
+				}
+			}
+			if options := parsed.OptionList(); options != nil {
+				detail.Options = options
+			}
+		}
+	}
 	return detail, nil
 }
 
@@ -59,36 +88,44 @@ type columnPragma struct {
 
 // GetTableColumns 获取表的列信息，包括 Unique 和 AutoIncrement
 func GetTableColumns(tableName string) ([]models.ColumnInfo, error) {
+	cols, _, err := loadTableColumns(tableName)
+	return cols, err
+}
+
+// loadTableColumns 获取列信息, 同时返回解析后的建表语句(视图或无法解析时为 nil)
+func loadTableColumns(tableName string) ([]models.ColumnInfo, *createTableDDL, error) {
 	if !IsValidIdentifier(tableName) {
-		return nil, fmt.Errorf("invalid table name: %s", tableName)
+		return nil, nil, fmt.Errorf("invalid table name: %s", tableName)
 	}
 
-	// Step 1: 获取表的 CREATE TABLE 语句
+	// Step 1: 获取表的 CREATE TABLE 语句(视图为 CREATE VIEW)
 	var ddl string
-	err := utils.DB.Get(&ddl, "SELECT sql FROM sqlite_master WHERE type='table' AND name=?", tableName)
+	err := utils.DB.Get(&ddl, "SELECT sql FROM sqlite_master WHERE type IN ('table', 'view') AND name=?", tableName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get table DDL: %w", err)
+		return nil, nil, fmt.Errorf("failed to get table DDL: %w", err)
 	}
 
+	parsed := parseTableDefinition(ddl)
+
 	// Step 2: 使用 PRAGMA table_xinfo 获取列基本信息
-	query := fmt.Sprintf("PRAGMA table_xinfo('%s')", tableName)
-	rows, err := utils.DB.Queryx(query)
+	query := "SELECT cid, name, type, \"notnull\", dflt_value, pk, hidden FROM pragma_table_xinfo(?)"
+	rows, err := utils.DB.Queryx(query, tableName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query table_xinfo: %w", err)
+		return nil, nil, fmt.Errorf("failed to query table_xinfo: %w", err)
 	}
 	defer rows.Close()
 
 	// Step 3: 获取 UNIQUE 列信息
 	uniqueColumns, err := getUniqueColumns(tableName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get unique columns: %w", err)
+		return nil, nil, fmt.Errorf("failed to get unique columns: %w", err)
 	}
 
 	var result []models.ColumnInfo
 	for rows.Next() {
 		var col columnPragma
 		if err := rows.StructScan(&col); err != nil {
-			return nil, fmt.Errorf("failed to scan row: %w", err)
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
 		}
 
 		defaultVal := ""
@@ -96,29 +133,39 @@ func GetTableColumns(tableName string) ([]models.ColumnInfo, error) {
 			defaultVal = col.DefaultValue.String
 		}
 
-		// 判断是否为 AUTOINCREMENT
-		isAutoIncrement := isColumnAutoIncrement(col.Name, ddl)
-
 		// 判断是否为 UNIQUE（包括主键）
 		isUnique := col.PK == 1 || uniqueColumns[col.Name]
 
-		result = append(result, models.ColumnInfo{
-			CID:           col.CID,
-			Name:          col.Name,
-			Type:          col.Type,
-			Unique:        isUnique,
-			NotNull:       col.NotNull == 1,
-			Default:       defaultVal,
-			Primary:       col.PK == 1,
-			AutoIncrement: isAutoIncrement,
-		})
+		info := models.ColumnInfo{
+			CID:     col.CID,
+			Name:    col.Name,
+			Type:    col.Type,
+			Unique:  isUnique,
+			NotNull: col.NotNull == 1,
+			Default: defaultVal,
+			Primary: col.PK == 1,
+			Hidden:  col.Hidden,
+		}
+		switch col.Hidden {
+		case 2:
+			info.Generated = "VIRTUAL"
+		case 3:
+			info.Generated = "STORED"
+		}
+		// 从列定义中解析 AUTOINCREMENT、排序规则、CHECK 和生成列表达式
+		if parsed != nil {
+			if item := parsed.Column(col.Name); item != nil {
+				applyColumnConstraints(&info, item.ColumnConstraints())
+			}
+		}
+		result = append(result, info)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("row iteration error: %w", err)
+		return nil, nil, fmt.Errorf("row iteration error: %w", err)
 	}
 
-	return result, nil
+	return result, parsed, nil
 }
 
 // getUniqueColumns 返回表中所有被 UNIQUE 约束覆盖的列（单列 UNIQUE）
@@ -136,63 +183,90 @@ func getUniqueColumns(tableName string) (map[string]bool, error) {
 	}
 
 	for _, index := range tableIndexes {
-		if index.Unique {
-			// 获取索引的列信息
-			cols, err := getIndexColumns(index.Name)
-			if err != nil {
-				return nil, err
-			}
-			if len(cols) == 1 {
-				uniqueCols[cols[0]] = true
-			}
+		// 部分索引和表达式索引不保证列唯一
+		if index.Unique && !index.Partial && len(index.Items) == 1 && index.Items[0].Column != "" {
+			uniqueCols[index.Items[0].Column] = true
 		}
 	}
 
 	return uniqueCols, nil
 }
 
-// 检查列是否为 AUTOINCREMENT
-func isColumnAutoIncrement(columnName, createTableSQL string) bool {
-	// 转换为大写进行比较
-	sqlUpper := strings.ToUpper(createTableSQL)
-	columnUpper := strings.ToUpper(columnName)
-
-	// 查找列定义
-	columnPattern := fmt.Sprintf(`\b%s\b[^,]*`, regexp.QuoteMeta(columnUpper))
-	re := regexp.MustCompile(columnPattern)
-
-	match := re.FindString(sqlUpper)
-	if match == "" {
-		return false
+// applyColumnConstraints 将列定义中的约束写入列信息
+func applyColumnConstraints(info *models.ColumnInfo, constraints []models.Constraint) {
+	info.Constraints = constraints
+	for _, c := range constraints {
+		switch c.Type {
+		case "PRIMARY KEY":
+			for _, t := range significantTokens(tokenizeSQL(c.SQL)) {
+				if t.Is("AUTOINCREMENT") {
+					info.AutoIncrement = true
+				}
+			}
+		case "COLLATE":
+			info.Collate = c.Expr
+		case "CHECK":
+			info.Checks = append(info.Checks, c.Expr)
+		case "GENERATED":
+			info.Expression = c.Expr
+		}
 	}
-
-	// 检查是否包含 AUTOINCREMENT 关键字
-	return strings.Contains(match, "AUTOINCREMENT") || strings.Contains(match, "AUTO_INCREMENT")
 }
 
 type NewTableColumnSchema struct {
 	Name          string `json:"name" validate:"required"`
-	Type          string `json:"type" validate:"required"`
+	Type          string `json:"type" validate:"required_without=Expression"`
 	NotNull       bool   `json:"notNull"`
 	Default       string `json:"default,omitempty"`
 	Primary       bool   `json:"pk,omitempty"`
 	AutoIncrement bool   `json:"autoIncrement,omitempty"` // 允许不传, 默认 false
+	Expression    string `json:"expression,omitempty"`    // 设置后添加生成列, 值由表达式计算
+	Generated     string `json:"generated,omitempty"`     // 生成列类型, ALTER TABLE 只能添加 VIRTUAL
 }
 
 // 新建表字段
 func NewTableColumn(tableName string, column NewTableColumnSchema) error {
+	sql, err := NewTableColumnSQL(tableName, column)
+	if err != nil {
+		return err
+	}
+	_, err = utils.DB.Exec(sql)
+	return err
+}
+
+// NewTableColumnSQL 生成新建表字段的 SQL
+func NewTableColumnSQL(tableName string, column NewTableColumnSchema) (string, error) {
 	if !IsValidIdentifier(tableName) {
-		return fmt.Errorf("invalid table name: %s", tableName)
+		return "", fmt.Errorf("invalid table name: %s", tableName)
 	}
 	if !IsValidIdentifier(column.Name) {
-		return fmt.Errorf("invalid column name: %s", column.Name)
+		return "", fmt.Errorf("invalid column name: %s", column.Name)
+	}
+	sql := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", utils.QuoteIdentifier(tableName), utils.QuoteIdentifier(column.Name))
+	if column.Type != "" {
+		sql += " " + column.Type
 	}
-	sql := fmt.Sprintf("ALTER TABLE \"%s\" ADD COLUMN \"%s\" %s", tableName, column.Name, column.Type)
 	if column.NotNull {
 		sql += " NOT NULL"
 	}
+	if expr := strings.TrimSpace(column.Expression); expr != "" {
+		switch strings.ToUpper(column.Generated) {
+		case "", "VIRTUAL":
+		case "STORED":
+			return "", fmt.Errorf("STORED generated columns cannot be added to an existing table, use VIRTUAL")
+		default:
+			return "", fmt.Errorf("invalid generated column type: %s", column.Generated)
+		}
+		if column.Default != "" || column.Primary {
+			return "", fmt.Errorf("generated columns cannot have a default value or be a primary key")
+		}
+		if err := validateExpr(expr); err != nil {
+			return "", fmt.Errorf("invalid expression: %w", err)
+		}
+		return sql + " GENERATED ALWAYS AS (" + expr + ") VIRTUAL", nil
+	}
 	if column.Default != "" {
-		sql += fmt.Sprintf(" DEFAULT '%s'", column.Default)
+		sql += " DEFAULT " + quoteLiteral(column.Default)
 	}
 	if column.Primary {
 		sql += " PRIMARY KEY"
@@ -200,21 +274,31 @@ func NewTableColumn(tableName string, column NewTableColumnSchema) error {
 			sql += " AUTOINCREMENT"
 		}
 	}
-	_, err := utils.DB.Exec(sql)
-	return err
+	return sql, nil
 }
 
 // 删除表字段
 func DeleteTableColumn(tableName, columnName string) error {
+	sql, err := DeleteTableColumnSQL(tableName, columnName)
+	if err != nil {
+		return err
+	}
+	if err := checkDisabledTriggerColumns(tableName, []string{columnName}); err != nil {
+		return err
+	}
+	_, err = utils.DB.Exec(sql)
+	return err
+}
+
+// DeleteTableColumnSQL 生成删除表字段的 SQL
+func DeleteTableColumnSQL(tableName, columnName string) (string, error) {
 	if !IsValidIdentifier(tableName) {
-		return fmt.Errorf("invalid table name: %s", tableName)
+		return "", fmt.Errorf("invalid table name: %s", tableName)
 	}
 	if !IsValidIdentifier(columnName) {
-		return fmt.Errorf("invalid column name: %s", columnName)
+		return "", fmt.Errorf("invalid column name: %s", columnName)
 	}
-	sql := fmt.Sprintf("ALTER TABLE \"%s\" DROP COLUMN \"%s\"", tableName, columnName)
-	_, err := utils.DB.Exec(sql)
-	return err
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", utils.QuoteIdentifier(tableName), utils.QuoteIdentifier(columnName)), nil
 }
 
 // 表字段重命名
@@ -225,7 +309,12 @@ func RenameTableColumn(tableName, oldName, newName string) error {
 	if !IsValidIdentifier(oldName) || !IsValidIdentifier(newName) {
 		return fmt.Errorf("invalid column name")
 	}
-	sql := fmt.Sprintf("ALTER TABLE \"%s\" RENAME COLUMN \"%s\" TO \"%s\"", tableName, oldName, newName)
+	if !strings.EqualFold(oldName, newName) {
+		if err := checkDisabledTriggerColumns(tableName, []string{oldName}); err != nil {
+			return err
+		}
+	}
+	sql := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", utils.QuoteIdentifier(tableName), utils.QuoteIdentifier(oldName), utils.QuoteIdentifier(newName))
 	_, err := utils.DB.Exec(sql)
 	return err
 }
@@ -246,14 +335,14 @@ func GetTableIndexes(tableName string) ([]models.IndexInfo, error) {
 	}
 
 	// Step 1: 获取索引列表（index_list）
-	query := fmt.Sprintf("PRAGMA index_list('%s')", tableName)
+	query := `SELECT seq, name, "unique", origin, partial FROM pragma_index_list(?)`
 	var pragmas []indexListPragma
-	if err := utils.DB.Select(&pragmas, query); err != nil {
+	if err := utils.DB.Select(&pragmas, query, tableName); err != nil {
 		return nil, fmt.Errorf("failed to get index list: %w", err)
 	}
 	indexes := make([]models.IndexInfo, 0, len(pragmas))
 	for _, p := range pragmas {
-		// 获取索引的 SQL
+		// 获取索引的 SQL, 约束自动创建的索引没有 SQL
 		var sqlNull sql.NullString
 		err := utils.DB.Get(&sqlNull, "SELECT sql FROM sqlite_master WHERE type='index' AND name=?", p.Name)
 
@@ -262,17 +351,28 @@ func GetTableIndexes(tableName string) ([]models.IndexInfo, error) {
 			indexSQL = sqlNull.String
 		}
 
-		// 获取索引的列
-		columns, err := getIndexColumns(p.Name)
+		// 获取索引的列、表达式、排序和排序规则
+		items, where, err := getIndexItems(p.Name, indexSQL)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get columns for index %s: %w", p.Name, err)
 		}
+		columns := make([]string, len(items))
+		for i, item := range items {
+			columns[i] = item.Column
+			if item.Expr != "" {
+				columns[i] = item.Expr
+			}
+		}
 
 		indexes = append(indexes, models.IndexInfo{
 			Name:    p.Name,
 			Unique:  p.Unique == 1,
 			SQL:     indexSQL,
 			Columns: columns,
+			Origin:  p.Origin,
+			Partial: p.Partial == 1,
+			Where:   where,
+			Items:   items,
 		})
 	}
 
@@ -280,72 +380,87 @@ func GetTableIndexes(tableName string) ([]models.IndexInfo, error) {
 }
 
 type NewTableIndexSchema struct {
-	Name    string   `json:"name" validate:"required"`
-	Columns []string `json:"columns" validate:"required"`
-	Unique  bool     `json:"unique"`
+	Name        string              `json:"name" validate:"required"`
+	Columns     []string            `json:"columns,omitempty" validate:"required_without=Items"` // 普通列, 按默认排序
+	Items       []IndexColumnSchema `json:"items,omitempty"`                                     // 列或表达式, 可指定排序和排序规则, 排在 Columns 之后
+	Unique      bool                `json:"unique"`
+	Where       string              `json:"where,omitempty"` // 部分索引的条件
+	IfNotExists bool                `json:"ifNotExists,omitempty"`
 }
 
 // 新建表索引
 func NewTableIndex(tableName string, index NewTableIndexSchema) error {
+	sql, err := NewTableIndexSQL(tableName, index)
+	if err != nil {
+		return err
+	}
+	_, err = utils.DB.Exec(sql)
+	return err
+}
+
+// NewTableIndexSQL 生成新建表索引的 SQL
+func NewTableIndexSQL(tableName string, index NewTableIndexSchema) (string, error) {
 	if !IsValidIdentifier(tableName) {
-		return fmt.Errorf("invalid table name: %s", tableName)
+		return "", fmt.Errorf("invalid table name: %s", tableName)
 	}
 	if !IsValidIdentifier(index.Name) {
-		return fmt.Errorf("invalid index name: %s", index.Name)
+		return "", fmt.Errorf("invalid index name: %s", index.Name)
+	}
+	if len(index.Columns) == 0 && len(index.Items) == 0 {
+		return "", fmt.Errorf("at least one column or expression is required")
 	}
+	defs := make([]string, 0, len(index.Columns)+len(index.Items))
 	for _, col := range index.Columns {
 		if !IsValidIdentifier(col) {
-			return fmt.Errorf("invalid column name: %s", col)
+			return "", fmt.Errorf("invalid column name: %s", col)
 		}
+		defs = append(defs, utils.QuoteIdentifier(col))
 	}
-	var unique string
+	for _, item := range index.Items {
+		def, err := item.render()
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, def)
+	}
+
+	sql := "CREATE "
 	if index.Unique {
-		unique = "UNIQUE"
+		sql += "UNIQUE "
 	}
-	sql := fmt.Sprintf("CREATE %s INDEX \"%s\" ON \"%s\" (%s)", unique, index.Name, tableName, strings.Join(index.Columns, ", "))
-	_, err := utils.DB.Exec(sql)
-	return err
+	sql += "INDEX "
+	if index.IfNotExists {
+		sql += "IF NOT EXISTS "
+	}
+	sql += fmt.Sprintf("%s ON %s (%s)", utils.QuoteIdentifier(index.Name), utils.QuoteIdentifier(tableName), strings.Join(defs, ", "))
+	if where := strings.TrimSpace(index.Where); where != "" {
+		if err := validateExpr(where); err != nil {
+			return "", fmt.Errorf("invalid where: %w", err)
+		}
+		sql += " WHERE " + where
+	}
+	return sql, nil
 }
 
 // 删除表索引
 func DeleteTableIndex(tableName, indexName string) error {
-	if !IsValidIdentifier(tableName) {
-		return fmt.Errorf("invalid table name: %s", tableName)
-	}
-	if !IsValidIdentifier(indexName) {
-		return fmt.Errorf("invalid index name: %s", indexName)
+	sql, err := DeleteTableIndexSQL(tableName, indexName)
+	if err != nil {
+		return err
 	}
-	sql := fmt.Sprintf("DROP INDEX IF EXISTS \"%s\"", indexName)
-	_, err := utils.DB.Exec(sql)
+	_, err = utils.DB.Exec(sql)
 	return err
 }
 
-// indexInfoPragma 用于映射 PRAGMA index_info 返回的数据
-type indexInfoPragma struct {
-	SeqNo      int    `db:"seqno"`
-	Cid        int    `db:"cid"`
-	ColumnName string `db:"name"`
-}
-
-// getIndexColumns 获取某个索引包含的列名
-func getIndexColumns(indexName string) ([]string, error) {
-	if !IsValidIdentifier(indexName) {
-		return nil, fmt.Errorf("invalid index name: %s", indexName)
-	}
-
-	query := fmt.Sprintf("PRAGMA index_info('%s')", indexName)
-	var pragmas []indexInfoPragma
-	if err := utils.DB.Select(&pragmas, query); err != nil {
-		return nil, fmt.Errorf("failed to get index info: %w", err)
+// DeleteTableIndexSQL 生成删除表索引的 SQL
+func DeleteTableIndexSQL(tableName, indexName string) (string, error) {
+	if !IsValidIdentifier(tableName) {
+		return "", fmt.Errorf("invalid table name: %s", tableName)
 	}
-
-	// 提取列名
-	columns := make([]string, len(pragmas))
-	for i, p := range pragmas {
-		columns[i] = p.ColumnName
+	if !IsValidIdentifier(indexName) {
+		return "", fmt.Errorf("invalid index name: %s", indexName)
 	}
-
-	return columns, nil
+	return "DROP INDEX IF EXISTS " + utils.QuoteIdentifier(indexName), nil
 }
 
 // triggerSchema 用于映射 sqlite_master 表中的触发器数据
@@ -391,11 +506,14 @@ func InsertRow(tableName string, data map[string]any) (int64, error) {
 	if !IsValidIdentifier(tableName) {
 		return 0, fmt.Errorf("invalid table name: %s", tableName)
 	}
+	if err := ensureTable(tableName); err != nil {
+		return 0, err
+	}
 	if len(data) == 0 {
 		return 0, fmt.Errorf("no data provided for insertion")
 	}
 	// 2. 验证列是否存在
-	cols, err := GetTableColumns(tableName)
+	cols, ddl, err := loadTableColumns(tableName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get table columns: %w", err)
 	}
@@ -404,9 +522,19 @@ func InsertRow(tableName string, data map[string]any) (int64, error) {
 		columnMap[col.Name] = col
 	}
 
+	// 3. 按列的声明类型转换值, 再按表的 JSON Schema 校验
+	strict := ddl != nil && isStrictTable(ddl)
+	if errs := coerceRow(columnMap, data, strict); len(errs) > 0 {
+		return 0, errs
+	}
+	schema := buildJSONSchema(tableName, cols, ddl, nil)
+	if errs := validateRow(schema, data, false); len(errs) > 0 {
+		return 0, errs
+	}
+
 	columns := make([]string, 0, len(data))
 	values := make([]string, 0, len(data))
-	params := make(map[string]any)
+	args := make([]any, 0, len(data))
 	for k, v := range data {
 		if !IsValidIdentifier(k) {
 			return 0, fmt.Errorf("invalid column name: %s", k)
@@ -415,24 +543,26 @@ func InsertRow(tableName string, data map[string]any) (int64, error) {
 		if _, exists := columnMap[k]; !exists {
 			return 0, fmt.Errorf("column not found: %s", k)
 		}
-
-		// 处理 nil 值
-		if v == nil && columnMap[k].NotNull {
-			return 0, fmt.Errorf("column %s cannot be null", k)
+		// 生成列的值由表达式计算, 忽略传入的值
+		if columnMap[k].Generated != "" {
+			continue
 		}
-		columns = append(columns, fmt.Sprintf(`"%s"`, k))
-		values = append(values, ":"+k)
-		params[k] = v
+		columns = append(columns, utils.QuoteIdentifier(k))
+		values = append(values, "?")
+		args = append(args, v)
 	}
-	// 4. 执行 SQL
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("no writable columns provided, generated columns are computed automatically")
+	}
+	// 4. 执行 SQL, 列名可能含任意字符, 值使用位置参数绑定
 	query := fmt.Sprintf(
-		`INSERT INTO "%s" (%s) VALUES (%s)`,
-		tableName,
+		`INSERT INTO %s (%s) VALUES (%s)`,
+		utils.QuoteIdentifier(tableName),
 		strings.Join(columns, ", "),
 		strings.Join(values, ", "),
 	)
 
-	result, err := utils.DB.NamedExec(query, params)
+	result, err := utils.DB.Exec(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert row: %w", err)
 	}
@@ -451,11 +581,14 @@ func UpdateRow(tableName string, data map[string]any) (int64, error) {
 	if !IsValidIdentifier(tableName) {
 		return 0, fmt.Errorf("invalid table name: %s", tableName)
 	}
+	if err := ensureTable(tableName); err != nil {
+		return 0, err
+	}
 	if len(data) == 0 {
 		return 0, fmt.Errorf("no data provided for update")
 	}
 	// 查询表字段
-	cols, err := GetTableColumns(tableName)
+	cols, ddl, err := loadTableColumns(tableName)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get table columns: %w", err)
 	}
@@ -480,15 +613,22 @@ func UpdateRow(tableName string, data map[string]any) (int64, error) {
 			return 0, fmt.Errorf("column '%s' does not exist", k)
 		}
 	}
+	if errs := coerceRow(colMap, data, ddl != nil && isStrictTable(ddl)); len(errs) > 0 {
+		return 0, errs
+	}
+	schema := buildJSONSchema(tableName, cols, ddl, nil)
+	if errs := validateRow(schema, data, true); len(errs) > 0 {
+		return 0, errs
+	}
 
 	// 5. 构建 UPDATE 语句
 	var sets []string
-	params := make(map[string]any)
+	var args []any
 	for k, v := range data {
-		if _, exists := colMap[k]; exists {
+		if col, exists := colMap[k]; exists && col.Generated == "" {
 			if !isPrimaryKey(k, pkCols) {
-				sets = append(sets, fmt.Sprintf(`"%s" = :set_%s`, k, k))
-				params["set_"+k] = v
+				sets = append(sets, utils.QuoteIdentifier(k)+" = ?")
+				args = append(args, v)
 			}
 		}
 	}
@@ -499,20 +639,20 @@ func UpdateRow(tableName string, data map[string]any) (int64, error) {
 		if !ok {
 			return 0, fmt.Errorf("primary key column '%s' must be provided", pk)
 		}
-		where = append(where, fmt.Sprintf(`"%s" = :where_%s`, pk, pk))
-		params["where_"+pk] = val
+		where = append(where, utils.QuoteIdentifier(pk)+" = ?")
+		args = append(args, val)
 	}
 	if len(sets) == 0 {
 		return 0, fmt.Errorf("no columns to update")
 	}
 	// 6. 执行更新
 	query := fmt.Sprintf(
-		`UPDATE "%s" SET %s WHERE %s`,
-		tableName,
+		`UPDATE %s SET %s WHERE %s`,
+		utils.QuoteIdentifier(tableName),
 		strings.Join(sets, ", "),
 		strings.Join(where, " AND "),
 	)
-	result, err := utils.DB.NamedExec(query, params)
+	result, err := utils.DB.Exec(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to execute update: %w", err)
 	}
@@ -531,6 +671,9 @@ func DeleteRow(tableName string, data map[string]string) (int64, error) {
 	if !IsValidIdentifier(tableName) {
 		return 0, fmt.Errorf("invalid table name: %s", tableName)
 	}
+	if err := ensureTable(tableName); err != nil {
+		return 0, err
+	}
 	if len(data) == 0 {
 		return 0, fmt.Errorf("no data provided for deletion")
 	}
@@ -553,7 +696,7 @@ func DeleteRow(tableName string, data map[string]string) (int64, error) {
 	}
 	// 4. 构建 WHERE 子句和参数
 	var where []string
-	params := make(map[string]any)
+	var args []any
 	for _, pk := range pkCols {
 		if !IsValidIdentifier(pk) {
 			return 0, fmt.Errorf("invalid column name: %s", pk)
@@ -565,18 +708,18 @@ func DeleteRow(tableName string, data map[string]string) (int64, error) {
 		if !ok {
 			return 0, fmt.Errorf("primary key column '%s' must be provided for deletion", pk)
 		}
-		where = append(where, fmt.Sprintf(`"%s" = :pk_%s`, pk, pk))
-		params["pk_"+pk] = val
+		where = append(where, utils.QuoteIdentifier(pk)+" = ?")
+		args = append(args, val)
 	}
 
 	// 5. 执行删除
 	query := fmt.Sprintf(
-		`DELETE FROM "%s" WHERE %s`,
-		tableName,
+		`DELETE FROM %s WHERE %s`,
+		utils.QuoteIdentifier(tableName),
 		strings.Join(where, " AND "),
 	)
 
-	result, err := utils.DB.NamedExec(query, params)
+	result, err := utils.DB.Exec(query, args...)
 	if err != nil {
 		return 0, fmt.Errorf("failed to execute delete: %w", err)
 	}
@@ -584,25 +727,17 @@ func DeleteRow(tableName string, data map[string]string) (int64, error) {
 	return result.RowsAffected()
 }
 
-// IsValidIdentifier 检查标识符是否合法（简单实现）
+// IsValidIdentifier 检查名称能否作为 SQLite 标识符: 加引号后任意非空且不含 NUL 的字符串都合法,
+// 拼接 SQL 时必须使用 utils.QuoteIdentifier
 func IsValidIdentifier(s string) bool {
-	if s == "" {
-		return false
-	}
-	for i, c := range s {
-		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_') {
-			return false
-		}
-		if i == 0 && (c >= '0' && c <= '9') {
-			return false // 不能以数字开头
-		}
-	}
-	return true
+	return s != "" && utf8.ValidString(s) && !strings.ContainsRune(s, 0)
 }
 
 type QueryTableResult struct {
-	Data  []map[string]any
-	Total int
+	Data    []map[string]any
+	Total   int
+	Links   []map[string]*RowLink // 与 Data 对应, 外键列 -> 父行, 表没有外键时为 nil
+	Matches []*SearchMatch        // 与 Data 对应, 全文搜索时的排名和高亮片段
 }
 
 func GetTableData(tableName string, limit, offset int) (*QueryTableResult, error) {
@@ -610,14 +745,14 @@ func GetTableData(tableName string, limit, offset int) (*QueryTableResult, error
 		Data: make([]map[string]any, 0),
 	}
 	// 统计总数：标识符不能参数化，需拼接
-	countSQL := fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, tableName)
+	countSQL := "SELECT COUNT(*) FROM " + utils.QuoteIdentifier(tableName)
 	if err := utils.DB.Get(&result.Total, countSQL); err != nil {
 		return nil, fmt.Errorf("count failed: %w", err)
 	}
 
 	// Fetch data
 	// 查询数据：表名拼接，limit/offset 用参数绑定
-	dataSQL := fmt.Sprintf(`SELECT * FROM "%s" LIMIT ? OFFSET ?`, tableName)
+	dataSQL := "SELECT * FROM " + utils.QuoteIdentifier(tableName) + " LIMIT ? OFFSET ?"
 	rows, err := utils.DB.Queryx(dataSQL, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("query failed: %w", err)
@@ -637,14 +772,27 @@ func GetTableData(tableName string, limit, offset int) (*QueryTableResult, error
 		}
 		result.Data = append(result.Data, row)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	return result, rows.Err()
+	fks, err := loadForeignKeys(utils.DB, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(fks) > 0 {
+		result.Links = rowLinks(fks, result.Data)
+	}
+	return result, nil
 }
 
 // ParseJSON 解析为 []map[string]any
 func ParseJSON(r io.Reader) ([]map[string]any, error) {
 	var data []map[string]any
-	if err := json.NewDecoder(r).Decode(&data); err != nil {
+	decoder := json.NewDecoder(r)
+	// 保留数字原文, 避免大整数经 float64 丢失精度
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
 		return nil, err
 	}
 	return data, nil
@@ -699,6 +847,9 @@ func ImportToTable(ctx context.Context, fileReader io.Reader, fileType, tableNam
 	if !IsValidIdentifier(tableName) {
 		return nil, fmt.Errorf("非法表名: %s", tableName)
 	}
+	if err := ensureTable(tableName); err != nil {
+		return nil, err
+	}
 	records, err := parseFile(fileReader, fileType)
 	if err != nil {
 		return nil, err
@@ -739,25 +890,54 @@ func ImportToTable(ctx context.Context, fileReader io.Reader, fileType, tableNam
 			return result, err
 		}
 	}
+	// 跳过生成列, 其值由表达式计算
+	tableColumns, ddl, err := loadTableColumns(tableName)
+	if err != nil {
+		return nil, err
+	}
+	strict := ddl != nil && isStrictTable(ddl)
+	colMap := make(map[string]models.ColumnInfo)
+	generated := make(map[string]bool)
+	for _, col := range tableColumns {
+		colMap[col.Name] = col
+		if col.Generated != "" {
+			generated[col.Name] = true
+		}
+	}
+	columns = slices.DeleteFunc(columns, func(col string) bool { return generated[col] })
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("没有可导入的列")
+	}
 	// 构建 INSERT 语句
-	colNames := make([]string, len(columns))
 	colParams := make([]string, len(columns))
-	for i, col := range columns {
-		colNames[i] = fmt.Sprintf(`"%s"`, col)
-		colParams[i] = ":" + col
+	for i := range columns {
+		colParams[i] = "?"
 	}
 
 	query := fmt.Sprintf(
-		`INSERT INTO "%s" (%s) VALUES (%s)`,
-		tableName,
-		strings.Join(colNames, ","),
+		`INSERT INTO %s (%s) VALUES (%s)`,
+		utils.QuoteIdentifier(tableName),
+		strings.Join(quotedColumns(columns), ","),
 		strings.Join(colParams, ","),
 	)
 
-	// 执行批量插入
+	// 执行批量插入, 按列顺序绑定位置参数
 	failed := false
-	for _, record := range records {
-		_, err := tx.NamedExecContext(ctx, query, record)
+	for i, record := range records {
+		// 按列的声明类型转换值, CSV 中的值都是字符串
+		if errs := coerceRow(colMap, record, strict); len(errs) > 0 {
+			result.FailedCount++
+			if len(result.Errors) < 5 {
+				result.Errors = append(result.Errors, fmt.Sprintf("第 %d 行: %s", i+1, errs.Error()))
+			}
+			failed = true
+			continue
+		}
+		args := make([]any, len(columns))
+		for i, col := range columns {
+			args[i] = record[col]
+		}
+		_, err := tx.ExecContext(ctx, query, args...)
 		if err != nil {
 			result.FailedCount++
 			result.Errors = append(result.Errors, err.Error())
@@ -837,9 +1017,9 @@ func StreamExportTableData(ctx context.Context, tableName string, columns []stri
 	}
 	// 构建查询
 	query := fmt.Sprintf(
-		`SELECT %s FROM "%s"`,
+		`SELECT %s FROM %s`,
 		strings.Join(quotedColumns(columns), ","),
-		tableName,
+		utils.QuoteIdentifier(tableName),
 	)
 	// 使用 sqlx 查询
 	rows, err := utils.DB.QueryxContext(ctx, query)
@@ -958,7 +1138,7 @@ func streamCSV(rows *sqlx.Rows, columns []string, w io.Writer) error {
 func quotedColumns(columns []string) []string {
 	quoted := make([]string, len(columns))
 	for i, col := range columns {
-		quoted[i] = fmt.Sprintf(`"%s"`, col)
+		quoted[i] = utils.QuoteIdentifier(col)
 	}
 	return quoted
 }
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+// STRICT 表允许的列类型
+var strictTypes = map[string]bool{"INT": true, "INTEGER": true, "REAL": true, "TEXT": true, "BLOB": true, "ANY": true}
+
+// 可直接作为 DEFAULT 的字面量: 数字、NULL、TRUE/FALSE 和当前时间
+var defaultLiteralPattern = regexp.MustCompile(`(?i)^([+-]?(\d+(\.\d*)?|\.\d+)([eE][+-]?\d+)?|0x[0-9a-f]+|NULL|TRUE|FALSE|CURRENT_TIMESTAMP|CURRENT_DATE|CURRENT_TIME)$`)
+
+// quoteLiteral 将文本转为 SQL 字符串字面量
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// renderDefault 生成 DEFAULT 子句的值: 字面量原样保留, 括号包围的视为表达式, 其余按字符串处理
+func renderDefault(v string) (string, error) {
+	trimmed := strings.TrimSpace(v)
+	switch {
+	case defaultLiteralPattern.MatchString(trimmed):
+		return trimmed, nil
+	case strings.HasPrefix(trimmed, "(") && strings.HasSuffix(trimmed, ")"):
+		if err := validateExpr(trimmed); err != nil {
+			return "", fmt.Errorf("invalid default expression: %w", err)
+		}
+		return trimmed, nil
+	case len(trimmed) >= 2 && strings.HasPrefix(trimmed, "'") && strings.HasSuffix(trimmed, "'") &&
+		scanQuoted(trimmed, 0, '\'') == len(trimmed):
+		// 已经是字符串字面量
+		return trimmed, nil
+	}
+	return quoteLiteral(v), nil
+}
+
+// validateExpr 检查用户提供的 SQL 表达式: 非空、括号配对、不包含分号
+func validateExpr(expr string) error {
+	sig := significantTokens(tokenizeSQL(expr))
+	if len(sig) == 0 {
+		return fmt.Errorf("expression is empty")
+	}
+	depth := 0
+	for _, t := range sig {
+		switch {
+		case t.IsOp(";"):
+			return fmt.Errorf("expression must not contain ';'")
+		case t.IsOp("("):
+			depth++
+		case t.IsOp(")"):
+			depth--
+			if depth < 0 {
+				return fmt.Errorf("unbalanced parentheses")
+			}
+		case t.Kind == tokString && (len(t.Text) < 2 || !strings.HasSuffix(t.Text, "'")):
+			return fmt.Errorf("unterminated string literal")
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
+
+// quoteIdentifiers 为一组标识符加引号并用逗号连接
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = utils.QuoteIdentifier(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// validateTableDefinition 校验表定义: 标识符、列引用、主键和表选项之间的约束
+func validateTableDefinition(req *models.CreateTableRequest) error {
+	if !IsValidIdentifier(req.TableName) {
+		return fmt.Errorf("invalid table name: %s", req.TableName)
+	}
+	if len(req.Columns) == 0 {
+		return fmt.Errorf("at least one column is required")
+	}
+	columns := make(map[string]*models.TableColumn, len(req.Columns))
+	var inlinePK []string
+	for i := range req.Columns {
+		col := &req.Columns[i]
+		col.Type = strings.ToUpper(col.Type)
+		if !IsValidIdentifier(col.Name) {
+			return fmt.Errorf("invalid column name: %s", col.Name)
+		}
+		key := strings.ToLower(col.Name)
+		if columns[key] != nil {
+			return fmt.Errorf("duplicate column name: %s", col.Name)
+		}
+		columns[key] = col
+		if req.Strict && !strictTypes[col.Type] {
+			return fmt.Errorf("column %s: type %s is not allowed in a STRICT table", col.Name, col.Type)
+		}
+		if col.Primary {
+			inlinePK = append(inlinePK, col.Name)
+		}
+		if col.Check != "" {
+			if err := validateExpr(col.Check); err != nil {
+				return fmt.Errorf("column %s: invalid check: %w", col.Name, err)
+			}
+		}
+		if col.AutoIncrement && !(col.Primary && col.Type == "INTEGER") {
+			return fmt.Errorf("column %s: AUTOINCREMENT is only allowed on an INTEGER PRIMARY KEY", col.Name)
+		}
+		if col.AutoIncrement && req.WithoutRowID {
+			return fmt.Errorf("column %s: AUTOINCREMENT is not allowed in a WITHOUT ROWID table", col.Name)
+		}
+	}
+
+	checkColumns := func(what string, names []string) error {
+		if len(names) == 0 {
+			return fmt.Errorf("%s: at least one column is required", what)
+		}
+		seen := make(map[string]bool, len(names))
+		for _, name := range names {
+			key := strings.ToLower(name)
+			if columns[key] == nil {
+				return fmt.Errorf("%s: no such column: %s", what, name)
+			}
+			if seen[key] {
+				return fmt.Errorf("%s: duplicate column: %s", what, name)
+			}
+			seen[key] = true
+		}
+		return nil
+	}
+
+	switch {
+	case len(inlinePK) > 1:
+		return fmt.Errorf("multiple columns marked primary, use primaryKey for a composite primary key")
+	case len(inlinePK) == 1 && len(req.PrimaryKey) > 0:
+		return fmt.Errorf("primaryKey cannot be combined with a column marked primary")
+	case len(req.PrimaryKey) > 0:
+		if err := checkColumns("primary key", req.PrimaryKey); err != nil {
+			return err
+		}
+	case req.WithoutRowID && len(inlinePK) == 0:
+		return fmt.Errorf("a WITHOUT ROWID table must have a primary key")
+	}
+	for _, unique := range req.Uniques {
+		if err := checkColumns("unique", unique); err != nil {
+			return err
+		}
+	}
+	for _, check := range req.Checks {
+		if err := validateExpr(check); err != nil {
+			return fmt.Errorf("invalid check: %w", err)
+		}
+	}
+	for _, fk := range req.ForeignKeys {
+		if err := checkColumns("foreign key", fk.Columns); err != nil {
+			return err
+		}
+		if !IsValidIdentifier(fk.RefTable) {
+			return fmt.Errorf("foreign key: invalid referenced table: %s", fk.RefTable)
+		}
+		if len(fk.RefColumns) > 0 && len(fk.RefColumns) != len(fk.Columns) {
+			return fmt.Errorf("foreign key: %d columns reference %d columns", len(fk.Columns), len(fk.RefColumns))
+		}
+		for _, ref := range fk.RefColumns {
+			if !IsValidIdentifier(ref) {
+				return fmt.Errorf("foreign key: invalid referenced column: %s", ref)
+			}
+		}
+	}
+	return nil
+}
+
+// renderColumnDef 生成单个列定义
+func renderColumnDef(col *models.TableColumn) (string, error) {
+	parts := []string{utils.QuoteIdentifier(col.Name), col.Type}
+	if col.Primary {
+		parts = append(parts, "PRIMARY KEY")
+		if col.AutoIncrement {
+			parts = append(parts, "AUTOINCREMENT")
+		}
+	}
+	if col.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	if col.Unique {
+		parts = append(parts, "UNIQUE")
+	}
+	if col.Default != nil {
+		def, err := renderDefault(*col.Default)
+		if err != nil {
+			return "", fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		parts = append(parts, "DEFAULT "+def)
+	}
+	if col.Check != "" {
+		parts = append(parts, "CHECK ("+col.Check+")")
+	}
+	if col.Collate != "" {
+		parts = append(parts, "COLLATE "+col.Collate)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// renderForeignKey 生成表级外键约束
+func renderForeignKey(fk *models.ForeignKey) string {
+	sql := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s", quoteIdentifiers(fk.Columns), utils.QuoteIdentifier(fk.RefTable))
+	if len(fk.RefColumns) > 0 {
+		sql += " (" + quoteIdentifiers(fk.RefColumns) + ")"
+	}
+	if fk.OnDelete != "" {
+		sql += " ON DELETE " + fk.OnDelete
+	}
+	if fk.OnUpdate != "" {
+		sql += " ON UPDATE " + fk.OnUpdate
+	}
+	return sql
+}
+
+// BuildCreateTableSQL 校验表定义并生成 CREATE TABLE 语句
+func BuildCreateTableSQL(req *models.CreateTableRequest) (string, error) {
+	if err := validateTableDefinition(req); err != nil {
+		return "", err
+	}
+	var defs []string
+	for i := range req.Columns {
+		def, err := renderColumnDef(&req.Columns[i])
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, def)
+	}
+	if len(req.PrimaryKey) > 0 {
+		defs = append(defs, "PRIMARY KEY ("+quoteIdentifiers(req.PrimaryKey)+")")
+	}
+	for _, unique := range req.Uniques {
+		defs = append(defs, "UNIQUE ("+quoteIdentifiers(unique)+")")
+	}
+	for _, check := range req.Checks {
+		defs = append(defs, "CHECK ("+check+")")
+	}
+	for i := range req.ForeignKeys {
+		defs = append(defs, renderForeignKey(&req.ForeignKeys[i]))
+	}
+
+	sql := fmt.Sprintf("CREATE TABLE %s (\n\t%s\n)", utils.QuoteIdentifier(req.TableName), strings.Join(defs, ",\n\t"))
+	var options []string
+	if req.Strict {
+		options = append(options, "STRICT")
+	}
+	if req.WithoutRowID {
+		options = append(options, "WITHOUT ROWID")
+	}
+	if len(options) > 0 {
+		sql += " " + strings.Join(options, ", ")
+	}
+	return sql, nil
+}
+
+// defaultTableColumns 未指定列时的默认定义
+func defaultTableColumns() []models.TableColumn {
+	return []models.TableColumn{{Name: "id", Type: "INTEGER", Primary: true, AutoIncrement: true}}
+}
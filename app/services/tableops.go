@@ -0,0 +1,298 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// CopyTableResult 复制表的结果
+type CopyTableResult struct {
+	Table string   `json:"table"`
+	SQL   []string `json:"sql"`
+	Rows  int64    `json:"rows"` // 复制的行数
+}
+
+// TruncateTableResult 清空表的结果
+type TruncateTableResult struct {
+	Table         string `json:"table"`
+	Rows          int64  `json:"rows"` // 删除的行数
+	ResetSequence bool   `json:"resetSequence"`
+}
+
+// 其后紧跟表名的关键字, UPDATE OR REPLACE 等冲突子句单独处理
+var tableRefKeywords = []string{"ON", "REFERENCES", "INTO", "FROM", "JOIN", "UPDATE"}
+
+// tableExists 判断表或视图是否存在
+func tableExists(db sqlx.Queryer, name string) (bool, error) {
+	var exists bool
+	err := sqlx.Get(db, &exists, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type IN ('table', 'view') AND name = ? COLLATE NOCASE)", name)
+	if err != nil {
+		return false, fmt.Errorf("failed to check table: %w", err)
+	}
+	return exists, nil
+}
+
+// execStatements 依次执行语句
+func execStatements(db sqlx.Execer, statements []string) error {
+	for _, stmt := range statements {
+		if _, err := db.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to execute %q: %w", firstLine(stmt), err)
+		}
+	}
+	return nil
+}
+
+// RenameTableSQL 生成表改名语句, 表有全文索引时包含索引的删除和重建
+// legacy_alter_table=OFF(默认)时 SQLite 会同时改写视图、触发器和外键中对该表的引用
+func RenameTableSQL(table, newName string) ([]string, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	if !IsValidIdentifier(newName) {
+		return nil, fmt.Errorf("invalid new table name: %s", newName)
+	}
+	before, after, err := renameFTSIndexSQL(utils.DB, table, newName)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fmt.Sprintf("ALTER TABLE %s RENAME TO %s", utils.QuoteIdentifier(table), utils.QuoteIdentifier(newName))
+	return append(append(before, stmt), after...), nil
+}
+
+// RenameTable 在事务中重命名表
+func RenameTable(table, newName string) error {
+	statements, err := RenameTableSQL(table, newName)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(table, newName) {
+		exists, err := tableExists(utils.DB, newName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("table %s already exists", newName)
+		}
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := execStatements(tx, statements); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	return renameDisabledTriggers(table, newName)
+}
+
+// copiedObjectName 为复制出的索引或触发器命名: 名称中以 _ 分隔的部分等于原表名时替换为新表名, 否则加上新表名前缀
+func copiedObjectName(name, table, newTable string) string {
+	lower, key := strings.ToLower(name), strings.ToLower(table)
+	for i := 0; i+len(key) <= len(lower); i++ {
+		end := i + len(key)
+		if lower[i:end] == key && (i == 0 || name[i-1] == '_') && (end == len(name) || name[end] == '_') {
+			return name[:i] + newTable + name[end:]
+		}
+	}
+	return newTable + "_" + name
+}
+
+// uniqueObjectName 名称已被占用时加数字后缀
+func uniqueObjectName(db sqlx.Queryer, name string, used map[string]bool) (string, error) {
+	candidate := name
+	for n := 2; ; n++ {
+		var exists bool
+		err := sqlx.Get(db, &exists, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE name = ? COLLATE NOCASE)", candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check name %s: %w", candidate, err)
+		}
+		if !exists && !used[strings.ToLower(candidate)] {
+			used[strings.ToLower(candidate)] = true
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s_%d", name, n)
+	}
+}
+
+// rewriteSchemaSQL 改写建表、索引或触发器语句: 对象名改为 newName, 表名位置上对 table 的引用改为 newTable
+// 只替换出现在 ON、FROM、INTO 等关键字之后的表名, 同名的列不受影响
+func rewriteSchemaSQL(sqlText, table, newTable, newName string) string {
+	tokens := tokenizeSQL(sqlText)
+	sig := significantTokens(tokens)
+	replace := make(map[int]string)
+	named := false
+	for i, t := range sig {
+		if !named && t.Is("TABLE", "INDEX", "TRIGGER") {
+			named = true
+			j := i + 1
+			if j+2 < len(sig) && sig[j].Is("IF") && sig[j+1].Is("NOT") && sig[j+2].Is("EXISTS") {
+				j += 3
+			}
+			if j < len(sig) && sig[j].IsIdent() {
+				replace[sig[j].Pos] = utils.QuoteIdentifier(newName)
+			}
+			continue
+		}
+		if i == 0 || !t.IsIdent() || !strings.EqualFold(t.Ident(), table) {
+			continue
+		}
+		prev := sig[i-1]
+		conflict := i >= 3 && sig[i-2].Is("OR") && sig[i-3].Is("UPDATE")
+		if prev.Is(tableRefKeywords...) || conflict {
+			replace[t.Pos] = utils.QuoteIdentifier(newTable)
+		}
+	}
+
+	var b strings.Builder
+	for _, t := range tokens {
+		if s, ok := replace[t.Pos]; ok {
+			b.WriteString(s)
+		} else {
+			b.WriteString(t.Text)
+		}
+	}
+	return b.String()
+}
+
+// CopyTableSQL 生成复制表的语句: 建表、可选的数据复制、索引和触发器
+func CopyTableSQL(table, newName string, withData bool) ([]string, error) {
+	return copyTableSQL(utils.DB, table, newName, withData)
+}
+
+func copyTableSQL(db sqlx.Queryer, table, newName string, withData bool) ([]string, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	if !IsValidIdentifier(newName) {
+		return nil, fmt.Errorf("invalid new table name: %s", newName)
+	}
+	var tableSQL string
+	if err := sqlx.Get(db, &tableSQL, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", table); err != nil {
+		return nil, fmt.Errorf("table not found: %s", table)
+	}
+	exists, err := tableExists(db, newName)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, fmt.Errorf("table %s already exists", newName)
+	}
+
+	statements := []string{rewriteSchemaSQL(tableSQL, table, newName, newName)}
+	if withData {
+		// 生成列和隐藏列不能插入
+		columns, err := tableColumnNames(db, table)
+		if err != nil {
+			return nil, err
+		}
+		quoted := make([]string, len(columns))
+		for i, col := range columns {
+			quoted[i] = utils.QuoteIdentifier(col)
+		}
+		cols := strings.Join(quoted, ", ")
+		statements = append(statements, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+			utils.QuoteIdentifier(newName), cols, cols, utils.QuoteIdentifier(table)))
+	}
+
+	// 自动索引(sql 为 NULL)由建表语句中的约束自动创建
+	var objects []schemaObject
+	err = sqlx.Select(db, &objects, `
+		SELECT type, name, tbl_name, sql
+		FROM sqlite_master
+		WHERE tbl_name = ? AND type IN ('index', 'trigger') AND sql IS NOT NULL
+		ORDER BY type, name
+	`, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load indexes and triggers: %w", err)
+	}
+	used := map[string]bool{strings.ToLower(newName): true}
+	for _, o := range objects {
+		// 全文索引的同步触发器写入原表的索引, 不复制
+		if o.Type == "trigger" && isFTSTrigger(table, o.Name) {
+			continue
+		}
+		name, err := uniqueObjectName(db, copiedObjectName(o.Name, table, newName), used)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, rewriteSchemaSQL(o.SQL.String, table, newName, name))
+	}
+	return statements, nil
+}
+
+// CopyTable 复制表结构(包含索引和触发器), withData 为 true 时同时复制数据
+func CopyTable(table, newName string, withData bool) (*CopyTableResult, error) {
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements, err := copyTableSQL(tx, table, newName, withData)
+	if err != nil {
+		return nil, err
+	}
+	if err := execStatements(tx, statements); err != nil {
+		return nil, err
+	}
+	result := &CopyTableResult{Table: newName, SQL: statements}
+	if err := tx.Get(&result.Rows, "SELECT count(*) FROM "+utils.QuoteIdentifier(newName)); err != nil {
+		return nil, fmt.Errorf("failed to count rows: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return result, nil
+}
+
+// TruncateTableSQL 生成清空表的语句, resetSequence 为 true 时同时重置 AUTOINCREMENT 计数
+func TruncateTableSQL(table string, resetSequence bool) ([]string, error) {
+	if !IsValidIdentifier(table) {
+		return nil, fmt.Errorf("invalid table name: %s", table)
+	}
+	statements := []string{"DELETE FROM " + utils.QuoteIdentifier(table)}
+	if resetSequence {
+		// 没有 AUTOINCREMENT 列的库中不存在 sqlite_sequence
+		var hasSequence bool
+		if err := utils.DB.Get(&hasSequence, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE name = 'sqlite_sequence')"); err != nil {
+			return nil, fmt.Errorf("failed to check sqlite_sequence: %w", err)
+		}
+		if hasSequence {
+			statements = append(statements, "DELETE FROM sqlite_sequence WHERE name = "+quoteLiteral(table))
+		}
+	}
+	return statements, nil
+}
+
+// TruncateTable 删除表中所有行
+func TruncateTable(table string, resetSequence bool) (*TruncateTableResult, error) {
+	statements, err := TruncateTableSQL(table, resetSequence)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result := &TruncateTableResult{Table: table, ResetSequence: resetSequence}
+	res, err := tx.Exec(statements[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete rows: %w", err)
+	}
+	result.Rows, _ = res.RowsAffected()
+	if err := execStatements(tx, statements[1:]); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+func TestCopiedObjectName(t *testing.T) {
+	tests := []struct {
+		name, table, newTable, want string
+	}{
+		{"idx_t_status", "t", "t2", "idx_t2_status"},
+		{"t_status_idx", "t", "t2", "t2_status_idx"},
+		{"idx_status_t", "t", "t2", "idx_status_t2"},
+		{"idx_status", "t", "t2", "t2_idx_status"},
+		{"idx_users_email", "user", "member", "member_idx_users_email"},
+		{"IDX_USERS_EMAIL", "users", "members", "IDX_members_EMAIL"},
+		{"users", "users", "members", "members"},
+		{"idx_订单_date", "订单", "订单2", "idx_订单2_date"},
+	}
+	for _, tt := range tests {
+		if got := copiedObjectName(tt.name, tt.table, tt.newTable); got != tt.want {
+			t.Errorf("copiedObjectName(%q, %q, %q) = %q, want %q", tt.name, tt.table, tt.newTable, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueObjectName(t *testing.T) {
+	openTestDB(t, `CREATE TABLE t (a); CREATE INDEX idx_a ON t (a); CREATE INDEX idx_a_2 ON t (a);`)
+	used := map[string]bool{"idx_b": true}
+	tests := []struct{ name, want string }{
+		{"idx_a", "idx_a_3"},
+		{"IDX_A", "IDX_A_4"},
+		{"idx_b", "idx_b_2"},
+		{"idx_c", "idx_c"},
+		{"idx_c", "idx_c_2"},
+	}
+	for _, tt := range tests {
+		got, err := uniqueObjectName(utils.DB, tt.name, used)
+		if err != nil || got != tt.want {
+			t.Errorf("uniqueObjectName(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestRewriteSchemaSQL(t *testing.T) {
+	tests := []struct {
+		sql, table, newTable, newName, want string
+	}{
+		{
+			`CREATE TABLE t (id INTEGER PRIMARY KEY, t TEXT)`, "t", "t2", "t2",
+			`CREATE TABLE "t2" (id INTEGER PRIMARY KEY, t TEXT)`,
+		},
+		{
+			`CREATE TABLE IF NOT EXISTS "t" (parent INTEGER REFERENCES t(id))`, "t", "t2", "t2",
+			`CREATE TABLE IF NOT EXISTS "t2" (parent INTEGER REFERENCES "t2"(id))`,
+		},
+		{
+			`CREATE UNIQUE INDEX idx_t_a ON t (a) WHERE t > 0`, "t", "t2", "idx_t2_a",
+			`CREATE UNIQUE INDEX "idx_t2_a" ON "t2" (a) WHERE t > 0`,
+		},
+		{
+			"CREATE TRIGGER trg AFTER INSERT ON [t] BEGIN\n  INSERT INTO log SELECT t FROM t; -- from t\n  UPDATE OR IGNORE t SET a = 't';\nEND",
+			"t", "t2", "trg2",
+			"CREATE TRIGGER \"trg2\" AFTER INSERT ON \"t2\" BEGIN\n  INSERT INTO log SELECT t FROM \"t2\"; -- from t\n  UPDATE OR IGNORE \"t2\" SET a = 't';\nEND",
+		},
+		{
+			`CREATE TRIGGER trg AFTER DELETE ON other BEGIN DELETE FROM 订单 WHERE id = old.id; END`, "订单", "orders", "trg",
+			`CREATE TRIGGER "trg" AFTER DELETE ON other BEGIN DELETE FROM "orders" WHERE id = old.id; END`,
+		},
+	}
+	for _, tt := range tests {
+		if got := rewriteSchemaSQL(tt.sql, tt.table, tt.newTable, tt.newName); got != tt.want {
+			t.Errorf("rewriteSchemaSQL(%q)\n got  %q\n want %q", tt.sql, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,432 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+)
+
+func init() {
+	storeSchemas = append(storeSchemas, `CREATE TABLE IF NOT EXISTS disabled_trigger (
+		db TEXT NOT NULL,
+		name TEXT NOT NULL,
+		tbl_name TEXT NOT NULL,
+		sql TEXT NOT NULL,
+		disabled_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (db, name)
+	)`)
+}
+
+// triggerDef 解析后的 CREATE TRIGGER 语句
+type triggerDef struct {
+	Name    string
+	Table   string
+	Timing  string   // BEFORE / AFTER / INSTEAD OF, 未声明时为 BEFORE
+	Event   string   // INSERT / UPDATE / DELETE
+	Columns []string // UPDATE OF 的列
+	When    string
+	Body    string // BEGIN 与 END 之间的语句
+}
+
+// parseTrigger 按 CREATE [TEMP] TRIGGER [IF NOT EXISTS] name [timing] event ON table [FOR EACH ROW] [WHEN expr] BEGIN ... END 解析触发器
+func parseTrigger(sqlText string) (*triggerDef, error) {
+	sig := significantTokens(tokenizeSQL(sqlText))
+	i := 0
+	expect := func(keywords ...string) bool {
+		if i < len(sig) && sig[i].Is(keywords...) {
+			i++
+			return true
+		}
+		return false
+	}
+	// ident 读取可能带 schema 前缀的名称
+	ident := func() (string, bool) {
+		if i >= len(sig) || !sig[i].IsIdent() {
+			return "", false
+		}
+		name := sig[i].Ident()
+		i++
+		if i+1 < len(sig) && sig[i].IsOp(".") && sig[i+1].IsIdent() {
+			name = sig[i+1].Ident()
+			i += 2
+		}
+		return name, true
+	}
+
+	if !expect("CREATE") {
+		return nil, fmt.Errorf("not a CREATE TRIGGER statement")
+	}
+	expect("TEMP", "TEMPORARY")
+	if !expect("TRIGGER") {
+		return nil, fmt.Errorf("not a CREATE TRIGGER statement")
+	}
+	if expect("IF") && !(expect("NOT") && expect("EXISTS")) {
+		return nil, fmt.Errorf("expected IF NOT EXISTS")
+	}
+	def := &triggerDef{Timing: "BEFORE"}
+	var ok bool
+	if def.Name, ok = ident(); !ok {
+		return nil, fmt.Errorf("missing trigger name")
+	}
+	switch {
+	case expect("BEFORE"):
+	case expect("AFTER"):
+		def.Timing = "AFTER"
+	case expect("INSTEAD"):
+		if !expect("OF") {
+			return nil, fmt.Errorf("expected INSTEAD OF")
+		}
+		def.Timing = "INSTEAD OF"
+	}
+	if i >= len(sig) || !sig[i].Is("INSERT", "UPDATE", "DELETE") {
+		return nil, fmt.Errorf("expected INSERT, UPDATE or DELETE")
+	}
+	def.Event = sig[i].Upper()
+	i++
+	if def.Event == "UPDATE" && expect("OF") {
+		for i < len(sig) && sig[i].IsIdent() && !sig[i].Is("ON") {
+			def.Columns = append(def.Columns, sig[i].Ident())
+			i++
+			if i < len(sig) && sig[i].IsOp(",") {
+				i++
+			}
+		}
+	}
+	if !expect("ON") {
+		return nil, fmt.Errorf("expected ON")
+	}
+	if def.Table, ok = ident(); !ok {
+		return nil, fmt.Errorf("missing table name")
+	}
+	if expect("FOR") && !(expect("EACH") && expect("ROW")) {
+		return nil, fmt.Errorf("expected FOR EACH ROW")
+	}
+
+	begin := -1
+	whenStart := -1
+	if i < len(sig) && sig[i].Is("WHEN") {
+		whenStart = i + 1
+	}
+	for j := i; j < len(sig); j++ {
+		if sig[j].Is("BEGIN") {
+			begin = j
+			break
+		}
+	}
+	if begin < 0 {
+		return nil, fmt.Errorf("missing BEGIN")
+	}
+	if whenStart >= 0 {
+		if whenStart >= begin {
+			return nil, fmt.Errorf("empty WHEN clause")
+		}
+		def.When = strings.TrimSpace(sqlText[sig[whenStart].Pos:sig[begin].Pos])
+	}
+	end := len(sig) - 1
+	if sig[end].IsOp(";") {
+		end--
+	}
+	if end <= begin || !sig[end].Is("END") {
+		return nil, fmt.Errorf("missing END")
+	}
+	def.Body = strings.TrimSpace(sqlText[sig[begin].Pos+len(sig[begin].Text) : sig[end].Pos])
+	return def, nil
+}
+
+// newTriggerModel 根据触发器 SQL 生成业务模型, 无法解析时只保留名称和 SQL
+func newTriggerModel(name, table, sqlText string) *models.Trigger {
+	trigger := &models.Trigger{
+		Name:       name,
+		Table:      table,
+		SQL:        sqlText,
+		Definition: formatTriggerDefinition(sqlText),
+	}
+	if def, err := parseTrigger(sqlText); err == nil {
+		trigger.Type = def.Event
+		trigger.Timing = def.Timing
+		trigger.Columns = def.Columns
+		trigger.When = def.When
+		trigger.Body = def.Body
+	}
+	return trigger
+}
+
+// BuildCreateTriggerSQL 校验请求并生成 CREATE TRIGGER 语句, 传入原始 SQL 时校验其为单条 CREATE TRIGGER
+func BuildCreateTriggerSQL(req *models.CreateTriggerRequest) (string, *triggerDef, error) {
+	if strings.TrimSpace(req.SQL) != "" {
+		stmt, err := singleStatement(req.SQL)
+		if err != nil {
+			return "", nil, err
+		}
+		def, err := parseTrigger(stmt)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid trigger: %w", err)
+		}
+		return stmt, def, nil
+	}
+
+	if !IsValidIdentifier(req.Name) {
+		return "", nil, fmt.Errorf("invalid trigger name: %s", req.Name)
+	}
+	if !IsValidIdentifier(req.Table) {
+		return "", nil, fmt.Errorf("invalid table name: %s", req.Table)
+	}
+	event := strings.ToUpper(req.Event)
+	if event == "" {
+		return "", nil, fmt.Errorf("event is required")
+	}
+	if len(req.Columns) > 0 && event != "UPDATE" {
+		return "", nil, fmt.Errorf("columns are only allowed for UPDATE triggers")
+	}
+	timing := strings.ToUpper(req.Timing)
+	if timing == "" {
+		timing = "AFTER"
+	}
+
+	sql := fmt.Sprintf("CREATE TRIGGER %s %s %s", utils.QuoteIdentifier(req.Name), timing, event)
+	if len(req.Columns) > 0 {
+		for _, col := range req.Columns {
+			if !IsValidIdentifier(col) {
+				return "", nil, fmt.Errorf("invalid column name: %s", col)
+			}
+		}
+		sql += " OF " + quoteIdentifiers(req.Columns)
+	}
+	sql += " ON " + utils.QuoteIdentifier(req.Table) + "\nFOR EACH ROW"
+	if strings.TrimSpace(req.When) != "" {
+		if err := validateExpr(req.When); err != nil {
+			return "", nil, fmt.Errorf("invalid when: %w", err)
+		}
+		sql += " WHEN " + strings.TrimSpace(req.When)
+	}
+
+	// 每条语句单独一行, 缺少的分号补上
+	var body []string
+	for _, stmt := range splitStatements(tokenizeSQL(req.Body)) {
+		text := strings.TrimSpace(joinTokens(stmt))
+		if !strings.HasSuffix(text, ";") {
+			text += ";"
+		}
+		body = append(body, "\t"+text)
+	}
+	if len(body) == 0 {
+		return "", nil, fmt.Errorf("trigger body is empty")
+	}
+	sql += "\nBEGIN\n" + strings.Join(body, "\n") + "\nEND"
+
+	def, err := parseTrigger(sql)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid trigger: %w", err)
+	}
+	return sql, def, nil
+}
+
+// joinTokens 拼接词法单元还原 SQL 文本
+func joinTokens(tokens []sqlToken) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
+
+// CreateTrigger 创建触发器
+func CreateTrigger(req *models.CreateTriggerRequest) (*models.Trigger, error) {
+	stmt, def, err := BuildCreateTriggerSQL(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := utils.DB.Exec(stmt); err != nil {
+		return nil, err
+	}
+	return newTriggerModel(def.Name, def.Table, stmt), nil
+}
+
+// DropTriggerSQL 生成删除触发器的语句
+func DropTriggerSQL(name string) (string, error) {
+	if !IsValidIdentifier(name) {
+		return "", fmt.Errorf("invalid trigger name: %s", name)
+	}
+	return "DROP TRIGGER " + utils.QuoteIdentifier(name), nil
+}
+
+// DropTrigger 删除触发器, 已停用的触发器从附属存储中删除
+func DropTrigger(name string) error {
+	stmt, err := DropTriggerSQL(name)
+	if err != nil {
+		return err
+	}
+	var exists bool
+	if err := utils.DB.Get(&exists, "SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = ?)", name); err != nil {
+		return fmt.Errorf("failed to check trigger: %w", err)
+	}
+	var disabled *models.Trigger
+	if utils.Store != nil {
+		if disabled, err = getDisabledTrigger(name); err != nil {
+			return err
+		}
+	}
+	if !exists && disabled == nil {
+		return fmt.Errorf("trigger not found: %s", name)
+	}
+	if disabled != nil {
+		if _, err := utils.Store.Exec("DELETE FROM disabled_trigger WHERE db = ? AND name = ?", utils.DBPath, name); err != nil {
+			return fmt.Errorf("failed to delete disabled trigger: %w", err)
+		}
+	}
+	if exists {
+		_, err = utils.DB.Exec(stmt)
+	}
+	return err
+}
+
+// disabledTriggerRow 用于映射 disabled_trigger 表
+type disabledTriggerRow struct {
+	Name  string `db:"name"`
+	Table string `db:"tbl_name"`
+	SQL   string `db:"sql"`
+}
+
+// getDisabledTrigger 查询当前数据库中已停用的触发器, 不存在时返回 nil
+func getDisabledTrigger(name string) (*models.Trigger, error) {
+	if err := requireStore(); err != nil {
+		return nil, err
+	}
+	var row disabledTriggerRow
+	err := utils.Store.Get(&row, "SELECT name, tbl_name, sql FROM disabled_trigger WHERE db = ? AND name = ?", utils.DBPath, name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load disabled trigger: %w", err)
+	}
+	trigger := newTriggerModel(row.Name, row.Table, row.SQL)
+	trigger.Disabled = true
+	return trigger, nil
+}
+
+// getDisabledTriggers 列出当前数据库中已停用的触发器, 附属存储不可用时返回空
+func getDisabledTriggers() ([]*models.Trigger, error) {
+	if utils.Store == nil {
+		return nil, nil
+	}
+	var rows []disabledTriggerRow
+	if err := utils.Store.Select(&rows, "SELECT name, tbl_name, sql FROM disabled_trigger WHERE db = ? ORDER BY name", utils.DBPath); err != nil {
+		return nil, fmt.Errorf("failed to load disabled triggers: %w", err)
+	}
+	triggers := make([]*models.Trigger, len(rows))
+	for i, row := range rows {
+		triggers[i] = newTriggerModel(row.Name, row.Table, row.SQL)
+		triggers[i].Disabled = true
+	}
+	return triggers, nil
+}
+
+// DisableTrigger 停用触发器: 定义保存到附属存储后删除触发器
+func DisableTrigger(name string) error {
+	if err := requireStore(); err != nil {
+		return err
+	}
+	stmt, err := DropTriggerSQL(name)
+	if err != nil {
+		return err
+	}
+	var row disabledTriggerRow
+	err = utils.DB.Get(&row, "SELECT name, tbl_name, sql FROM sqlite_master WHERE type = 'trigger' AND name = ?", name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("trigger not found: %s", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load trigger: %w", err)
+	}
+	_, err = utils.Store.Exec("INSERT OR REPLACE INTO disabled_trigger (db, name, tbl_name, sql) VALUES (?, ?, ?, ?)",
+		utils.DBPath, row.Name, row.Table, row.SQL)
+	if err != nil {
+		return fmt.Errorf("failed to save trigger: %w", err)
+	}
+	if _, err := utils.DB.Exec(stmt); err != nil {
+		_, _ = utils.Store.Exec("DELETE FROM disabled_trigger WHERE db = ? AND name = ?", utils.DBPath, name)
+		return err
+	}
+	return nil
+}
+
+// EnableTrigger 按保存的定义重新创建已停用的触发器
+func EnableTrigger(name string) error {
+	trigger, err := getDisabledTrigger(name)
+	if err != nil {
+		return err
+	}
+	if trigger == nil {
+		return fmt.Errorf("trigger %s is not disabled", name)
+	}
+	if _, err := utils.DB.Exec(trigger.SQL); err != nil {
+		return fmt.Errorf("failed to recreate trigger: %w", err)
+	}
+	_, err = utils.Store.Exec("DELETE FROM disabled_trigger WHERE db = ? AND name = ?", utils.DBPath, name)
+	return err
+}
+
+// renameDisabledTriggers 表改名后按 SQLite 改写现存触发器的方式同步改写已停用触发器的定义
+func renameDisabledTriggers(table, newTable string) error {
+	if utils.Store == nil {
+		return nil
+	}
+	var rows []disabledTriggerRow
+	if err := utils.Store.Select(&rows, "SELECT name, tbl_name, sql FROM disabled_trigger WHERE db = ?", utils.DBPath); err != nil {
+		return fmt.Errorf("failed to load disabled triggers: %w", err)
+	}
+	for _, row := range rows {
+		rewritten := rewriteSchemaSQL(row.SQL, table, newTable, row.Name)
+		// 表名未出现在表名位置上时定义不变
+		if rewritten == rewriteSchemaSQL(row.SQL, table, table, row.Name) {
+			continue
+		}
+		tblName := row.Table
+		if strings.EqualFold(tblName, table) {
+			tblName = newTable
+		}
+		_, err := utils.Store.Exec("UPDATE disabled_trigger SET tbl_name = ?, sql = ? WHERE db = ? AND name = ?",
+			tblName, rewritten, utils.DBPath, row.Name)
+		if err != nil {
+			return fmt.Errorf("failed to update disabled trigger %s: %w", row.Name, err)
+		}
+	}
+	return nil
+}
+
+// dropDisabledTriggers 删除表后清除其已停用触发器, 避免之后同名的新表继承过期的定义
+func dropDisabledTriggers(table string) error {
+	if utils.Store == nil {
+		return nil
+	}
+	if _, err := utils.Store.Exec("DELETE FROM disabled_trigger WHERE db = ? AND tbl_name = ? COLLATE NOCASE", utils.DBPath, table); err != nil {
+		return fmt.Errorf("failed to remove disabled triggers of %s: %w", table, err)
+	}
+	return nil
+}
+
+// checkDisabledTriggerColumns 涉及该表的已停用触发器引用了将被删除或改名的列时返回错误, 避免启用时按过期定义重建
+func checkDisabledTriggerColumns(table string, columns []string) error {
+	if utils.Store == nil || len(columns) == 0 {
+		return nil
+	}
+	var rows []disabledTriggerRow
+	if err := utils.Store.Select(&rows, "SELECT name, tbl_name, sql FROM disabled_trigger WHERE db = ?", utils.DBPath); err != nil {
+		return fmt.Errorf("failed to load disabled triggers: %w", err)
+	}
+	for _, row := range rows {
+		if !strings.EqualFold(row.Table, table) && !referencesName(row.SQL, table) {
+			continue
+		}
+		for _, col := range columns {
+			if referencesName(row.SQL, col) {
+				return fmt.Errorf("disabled trigger %s references column %s, enable or drop it first", row.Name, col)
+			}
+		}
+	}
+	return nil
+}
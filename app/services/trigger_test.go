@@ -0,0 +1,98 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseTrigger(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want triggerDef
+	}{
+		{
+			`CREATE TRIGGER trg AFTER INSERT ON t BEGIN INSERT INTO log VALUES (new.a); END`,
+			triggerDef{Name: "trg", Table: "t", Timing: "AFTER", Event: "INSERT", Body: "INSERT INTO log VALUES (new.a);"},
+		},
+		{
+			`CREATE TEMP TRIGGER IF NOT EXISTS main."trg 2" UPDATE OF a, "b-c" ON "订单" FOR EACH ROW WHEN new.a > old.a BEGIN SELECT 1; SELECT CASE WHEN 1 THEN 2 END; END;`,
+			triggerDef{Name: "trg 2", Table: "订单", Timing: "BEFORE", Event: "UPDATE", Columns: []string{"a", "b-c"},
+				When: "new.a > old.a", Body: "SELECT 1; SELECT CASE WHEN 1 THEN 2 END;"},
+		},
+		{
+			`CREATE TRIGGER v_ins INSTEAD OF DELETE ON v BEGIN DELETE FROM t WHERE id = old.id; END`,
+			triggerDef{Name: "v_ins", Table: "v", Timing: "INSTEAD OF", Event: "DELETE", Body: "DELETE FROM t WHERE id = old.id;"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseTrigger(tt.sql)
+		if err != nil {
+			t.Fatalf("parseTrigger(%q): %v", tt.sql, err)
+		}
+		if got.Name != tt.want.Name || got.Table != tt.want.Table || got.Timing != tt.want.Timing || got.Event != tt.want.Event ||
+			!slices.Equal(got.Columns, tt.want.Columns) || got.When != tt.want.When || got.Body != tt.want.Body {
+			t.Errorf("parseTrigger(%q)\n got  %+v\n want %+v", tt.sql, *got, tt.want)
+		}
+	}
+
+	for _, sql := range []string{
+		`CREATE TABLE t (a)`,
+		`CREATE TRIGGER trg AFTER TRUNCATE ON t BEGIN SELECT 1; END`,
+		`CREATE TRIGGER trg AFTER INSERT t BEGIN SELECT 1; END`,
+		`CREATE TRIGGER trg AFTER INSERT ON t SELECT 1`,
+		`CREATE TRIGGER trg AFTER INSERT ON t BEGIN SELECT 1;`,
+	} {
+		if _, err := parseTrigger(sql); err == nil {
+			t.Errorf("parseTrigger(%q) succeeded, want error", sql)
+		}
+	}
+}
+
+// disabledTriggerNames 返回附属存储中已停用触发器的名称
+func disabledTriggerNames(t *testing.T) []string {
+	t.Helper()
+	triggers, err := getDisabledTriggers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, trigger := range triggers {
+		names = append(names, trigger.Name+" ON "+trigger.Table)
+	}
+	return names
+}
+
+func TestDisabledTriggersFollowTable(t *testing.T) {
+	openTestDB(t, `
+		CREATE TABLE t (id INTEGER PRIMARY KEY, a TEXT);
+		CREATE TABLE log (x);
+		CREATE TRIGGER trg AFTER INSERT ON t BEGIN INSERT INTO log VALUES (new.a); END;
+	`)
+	openTestStore(t)
+
+	if err := DisableTrigger("trg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RenameTable("t", "t2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := disabledTriggerNames(t); !slices.Equal(got, []string{"trg ON t2"}) {
+		t.Errorf("after rename: %v", got)
+	}
+	if err := RenameTableColumn("t2", "a", "b"); err == nil {
+		t.Error("renaming a column used by a disabled trigger succeeded")
+	}
+	if err := EnableTrigger("trg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := DisableTrigger("trg"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := DropSQLiteTable("t2"); err != nil {
+		t.Fatal(err)
+	}
+	if got := disabledTriggerNames(t); len(got) != 0 {
+		t.Errorf("after drop: %v", got)
+	}
+}
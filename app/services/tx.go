@@ -0,0 +1,225 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// IdleTxTimeout 交互式事务的空闲超时, 超时后自动回滚
+var IdleTxTimeout = 5 * time.Minute
+
+// TxInfo 交互式事务信息
+type TxInfo struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Statements int       `json:"statements"` // 已执行的语句数
+}
+
+// txSession 绑定到独立连接的事务, 同一事务内的语句串行执行
+type txSession struct {
+	mu         sync.Mutex
+	id         string
+	tx         *sqlx.Tx
+	createdAt  time.Time
+	lastUsedAt time.Time
+	statements int
+	timer      *time.Timer
+	closed     bool
+}
+
+func (s *txSession) info() *TxInfo {
+	return &TxInfo{
+		ID:         s.id,
+		CreatedAt:  s.createdAt,
+		LastUsedAt: s.lastUsedAt,
+		ExpiresAt:  s.lastUsedAt.Add(IdleTxTimeout),
+		Statements: s.statements,
+	}
+}
+
+var txSessions = struct {
+	sync.Mutex
+	m map[string]*txSession
+}{m: make(map[string]*txSession)}
+
+func newTxID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate tx id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// BeginTx 开启一个交互式事务, 返回事务 ID
+func BeginTx() (*TxInfo, error) {
+	id, err := newTxID()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	now := time.Now()
+	s := &txSession{id: id, tx: tx, createdAt: now, lastUsedAt: now}
+	s.timer = time.AfterFunc(IdleTxTimeout, func() { expireTx(s) })
+
+	txSessions.Lock()
+	txSessions.m[id] = s
+	txSessions.Unlock()
+	return s.info(), nil
+}
+
+// expireTx 空闲超时回调, 期间被使用过则重新计时
+func expireTx(s *txSession) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if idle := time.Since(s.lastUsedAt); idle < IdleTxTimeout {
+		s.timer.Reset(IdleTxTimeout - idle)
+		return
+	}
+	if err := s.close(false); err != nil {
+		utils.GetLogger("").Warn("idle transaction rollback failed", "tx", s.id, "error", err)
+		return
+	}
+	utils.GetLogger("").Info("idle transaction rolled back", "tx", s.id)
+}
+
+// close 提交或回滚事务并移除会话, 调用方需持有 s.mu
+func (s *txSession) close(commit bool) error {
+	s.closed = true
+	s.timer.Stop()
+	txSessions.Lock()
+	delete(txSessions.m, s.id)
+	txSessions.Unlock()
+	if commit {
+		if err := s.tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		return nil
+	}
+	if err := s.tx.Rollback(); err != nil {
+		return fmt.Errorf("failed to rollback transaction: %w", err)
+	}
+	return nil
+}
+
+// withTx 锁定事务会话后执行 fn, 并在开始和结束时刷新最后使用时间, 耗时较长的流式查询结束后重新计算空闲时间
+func withTx(id string, fn func(s *txSession) error) error {
+	txSessions.Lock()
+	s, ok := txSessions.m[id]
+	txSessions.Unlock()
+	if !ok {
+		return fmt.Errorf("transaction not found or expired: %s", id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("transaction not found or expired: %s", id)
+	}
+	s.lastUsedAt = time.Now()
+	defer func() { s.lastUsedAt = time.Now() }()
+	return fn(s)
+}
+
+// GetTx 获取事务信息
+func GetTx(id string) (*TxInfo, error) {
+	var info *TxInfo
+	err := withTx(id, func(s *txSession) error {
+		info = s.info()
+		return nil
+	})
+	return info, err
+}
+
+// ListTx 列出所有未结束的事务
+func ListTx() []*TxInfo {
+	txSessions.Lock()
+	sessions := make([]*txSession, 0, len(txSessions.m))
+	for _, s := range txSessions.m {
+		sessions = append(sessions, s)
+	}
+	txSessions.Unlock()
+
+	result := make([]*TxInfo, 0, len(sessions))
+	for _, s := range sessions {
+		s.mu.Lock()
+		if !s.closed {
+			result = append(result, s.info())
+		}
+		s.mu.Unlock()
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result
+}
+
+// CommitTx 提交事务
+func CommitTx(id string) error {
+	return withTx(id, func(s *txSession) error { return s.close(true) })
+}
+
+// RollbackTx 回滚事务
+func RollbackTx(id string) error {
+	return withTx(id, func(s *txSession) error { return s.close(false) })
+}
+
+// isTxControl 判断是否含有 BEGIN/COMMIT/END/ROLLBACK 等会破坏事务会话的语句, SAVEPOINT 和 ROLLBACK TO 允许
+// 驱动会执行字符串中的每条语句, 因此逐条检查
+func isTxControl(sqlStr string) bool {
+	for _, stmt := range splitStatements(tokenizeSQL(sqlStr)) {
+		sig := significantTokens(stmt)
+		if len(sig) == 0 {
+			continue
+		}
+		switch {
+		case sig[0].Is("BEGIN", "COMMIT", "END"):
+			return true
+		case sig[0].Is("ROLLBACK") && !slices.ContainsFunc(sig[1:], func(t sqlToken) bool { return t.Is("TO") }):
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteSQLInTx 在交互式事务中执行 SQL, 可以看到事务内未提交的修改
+func ExecuteSQLInTx(id, sqlStr string, page, size int, args ...any) (*SQLResult, error) {
+	if isTxControl(sqlStr) {
+		return nil, fmt.Errorf("transaction control statements are not allowed, use commit/rollback endpoints")
+	}
+	var result *SQLResult
+	err := withTx(id, func(s *txSession) error {
+		s.statements++
+		result = executeSQL(s.tx, sqlStr, page, size, args...)
+		return nil
+	})
+	return result, err
+}
+
+// StreamSelectInTx 在交互式事务中流式执行 SELECT
+func StreamSelectInTx(id, sqlStr string, page, size int, format string, w io.Writer, args ...any) (*StreamEvent, error) {
+	var event *StreamEvent
+	var streamErr error
+	err := withTx(id, func(s *txSession) error {
+		s.statements++
+		event, streamErr = streamSelect(s.tx, sqlStr, page, size, format, w, args...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return event, streamErr
+}
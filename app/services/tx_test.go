@@ -0,0 +1,30 @@
+package services
+
+import "testing"
+
+func TestIsTxControl(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want bool
+	}{
+		{"SELECT 1", false},
+		{"UPDATE t SET a = 1", false},
+		{"BEGIN", true},
+		{"commit", true},
+		{"END TRANSACTION", true},
+		{"ROLLBACK", true},
+		{"ROLLBACK TO sp1", false},
+		{"ROLLBACK TRANSACTION TO SAVEPOINT sp1", false},
+		{"SAVEPOINT sp1", false},
+		{"RELEASE sp1", false},
+		{"UPDATE t SET a = 1; COMMIT", true},
+		{"SELECT 1; ROLLBACK;", true},
+		{"SELECT 'COMMIT'; -- COMMIT", false},
+		{"CREATE TRIGGER trg AFTER INSERT ON t BEGIN SELECT 1; END; SELECT 2", false},
+	}
+	for _, tt := range tests {
+		if got := isTxControl(tt.sql); got != tt.want {
+			t.Errorf("isTxControl(%q) = %v, want %v", tt.sql, got, tt.want)
+		}
+	}
+}
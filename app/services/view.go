@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/fuxingjun/go-sqlite-web/app/models"
+	"github.com/fuxingjun/go-sqlite-web/app/utils"
+	"github.com/jmoiron/sqlx"
+)
+
+// singleStatement 校验 SQL 只包含一条语句, 返回去掉结尾分号后的文本
+func singleStatement(sqlText string) (string, error) {
+	statements := splitStatements(tokenizeSQL(sqlText))
+	if len(statements) == 0 {
+		return "", fmt.Errorf("SQL is empty")
+	}
+	if len(statements) > 1 {
+		return "", fmt.Errorf("only one statement is allowed")
+	}
+	text := strings.TrimSpace(sqlText)
+	return strings.TrimSpace(strings.TrimSuffix(text, ";")), nil
+}
+
+// objectType 返回 sqlite_master 中对象的类型(table / view), 不存在时为空
+func objectType(db sqlx.Queryer, name string) (string, error) {
+	var types []string
+	if err := sqlx.Select(db, &types, "SELECT type FROM sqlite_master WHERE name = ? AND type IN ('table', 'view')", name); err != nil {
+		return "", fmt.Errorf("failed to check %s: %w", name, err)
+	}
+	if len(types) == 0 {
+		return "", nil
+	}
+	return types[0], nil
+}
+
+// ensureTable 写入操作前检查目标不是视图, 视图只能作为只读数据源
+func ensureTable(name string) error {
+	typ, err := objectType(utils.DB, name)
+	if err != nil {
+		return err
+	}
+	if typ == "view" {
+		return fmt.Errorf("'%s' is a view and is read-only", name)
+	}
+	return nil
+}
+
+// BuildCreateViewSQL 校验视图定义并生成 CREATE VIEW 语句
+func BuildCreateViewSQL(req *models.CreateViewRequest) (string, error) {
+	if !IsValidIdentifier(req.Name) {
+		return "", fmt.Errorf("invalid view name: %s", req.Name)
+	}
+	for _, col := range req.Columns {
+		if !IsValidIdentifier(col) {
+			return "", fmt.Errorf("invalid column name: %s", col)
+		}
+	}
+	query, err := singleStatement(req.SQL)
+	if err != nil {
+		return "", err
+	}
+	sig := significantTokens(tokenizeSQL(query))
+	if !sig[0].Is("SELECT", "WITH", "VALUES") {
+		return "", fmt.Errorf("view definition must be a SELECT statement")
+	}
+	sql := "CREATE VIEW " + utils.QuoteIdentifier(req.Name)
+	if len(req.Columns) > 0 {
+		sql += " (" + quoteIdentifiers(req.Columns) + ")"
+	}
+	return sql + " AS\n" + query, nil
+}
+
+// checkView 查询一次视图, 确认其引用的表和列都存在
+func checkView(db sqlx.Queryer, name string) error {
+	rows, err := db.Queryx("SELECT * FROM " + utils.QuoteIdentifier(name) + " LIMIT 0")
+	if err != nil {
+		return fmt.Errorf("invalid view %s: %w", name, err)
+	}
+	return rows.Close()
+}
+
+// CreateView 创建视图
+func CreateView(req *models.CreateViewRequest) error {
+	stmt, err := BuildCreateViewSQL(req)
+	if err != nil {
+		return err
+	}
+	exists, err := tableExists(utils.DB, req.Name)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("table or view %s already exists", req.Name)
+	}
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(stmt); err != nil {
+		return err
+	}
+	if err := checkView(tx, req.Name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DropViewSQL 生成删除视图的语句
+func DropViewSQL(name string) (string, error) {
+	if !IsValidIdentifier(name) {
+		return "", fmt.Errorf("invalid view name: %s", name)
+	}
+	return "DROP VIEW " + utils.QuoteIdentifier(name), nil
+}
+
+// DropView 删除视图, 视图上的 INSTEAD OF 触发器随之删除
+func DropView(name string) error {
+	stmt, err := DropViewSQL(name)
+	if err != nil {
+		return err
+	}
+	_, err = utils.DB.Exec(stmt)
+	return err
+}
+
+// UpdateViewSQL 生成修改视图的语句: 删除后按新定义重建, 并恢复视图上的触发器, req.Name 不同时同时改名
+func UpdateViewSQL(name string, req *models.CreateViewRequest) ([]string, error) {
+	return updateViewSQL(utils.DB, name, req)
+}
+
+func updateViewSQL(db sqlx.Queryer, name string, req *models.CreateViewRequest) ([]string, error) {
+	drop, err := DropViewSQL(name)
+	if err != nil {
+		return nil, err
+	}
+	typ, err := objectType(db, name)
+	if err != nil {
+		return nil, err
+	}
+	if typ != "view" {
+		return nil, fmt.Errorf("view not found: %s", name)
+	}
+	if !strings.EqualFold(name, req.Name) {
+		exists, err := tableExists(db, req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, fmt.Errorf("table or view %s already exists", req.Name)
+		}
+	}
+	create, err := BuildCreateViewSQL(req)
+	if err != nil {
+		return nil, err
+	}
+	statements := []string{drop, create}
+
+	var triggers []schemaObject
+	if err := sqlx.Select(db, &triggers, "SELECT type, name, tbl_name, sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ? ORDER BY name", name); err != nil {
+		return nil, fmt.Errorf("failed to load triggers: %w", err)
+	}
+	for _, t := range triggers {
+		statements = append(statements, rewriteSchemaSQL(t.SQL.String, name, req.Name, t.Name))
+	}
+	return statements, nil
+}
+
+// UpdateView 在事务中删除并重建视图, 新定义或依赖它的视图失效时回滚
+func UpdateView(name string, req *models.CreateViewRequest) error {
+	tx, err := utils.DB.Beginx()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements, err := updateViewSQL(tx, name, req)
+	if err != nil {
+		return err
+	}
+	if err := execStatements(tx, statements); err != nil {
+		return err
+	}
+	if err := checkView(tx, req.Name); err != nil {
+		return err
+	}
+	var views []schemaObject
+	if err := tx.Select(&views, "SELECT type, name, tbl_name, sql FROM sqlite_master WHERE type = 'view'"); err != nil {
+		return fmt.Errorf("failed to load views: %w", err)
+	}
+	for _, v := range views {
+		if referencesName(v.SQL.String, name) {
+			if err := checkView(tx, v.Name); err != nil {
+				return fmt.Errorf("view %s is broken by this change: %w", v.Name, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
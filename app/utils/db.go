@@ -12,12 +12,21 @@ import (
 var DB *sqlx.DB
 var DBPath string
 
+// ForeignKeys 是否开启外键约束(默认关闭, 与 SQLite 一致), 开启时通过连接参数应用到连接池中的每个连接, 需在 Connect 之前设置
+var ForeignKeys = false
+
 func Connect(path string, readOnly bool) error {
-	mode := ""
+	var params []string
+	if ForeignKeys {
+		params = append(params, "_pragma=foreign_keys(1)")
+	}
 	if readOnly {
-		mode = "?mode=ro"
+		params = append(params, "mode=ro")
+	}
+	dsn := "file:" + path
+	if len(params) > 0 {
+		dsn += "?" + strings.Join(params, "&")
 	}
-	dsn := fmt.Sprintf("file:%s%s", path, mode)
 	instance, err := sqlx.Connect("sqlite", dsn)
 	if err != nil {
 		return err
@@ -27,27 +36,20 @@ func Connect(path string, readOnly bool) error {
 	return nil
 }
 
+// QuoteIdentifier 为标识符加双引号, 内部的双引号转义为两个, 任意名称(中文、含 - 或空格)都可安全拼接到 SQL 中
 func QuoteIdentifier(name string) string {
-	// 仅允许字母、数字、下划线，且不能以数字开头
-	if !isValidIdentifier(name) {
-		panic("invalid table name")
-	}
 	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
 
-func isValidIdentifier(name string) bool {
-	if name == "" || len(name) > 128 {
-		return false
-	}
-	for i, r := range name {
-		switch {
-		case r >= 'a' && r <= 'z':
-		case r >= 'A' && r <= 'Z':
-		case r >= '0' && r <= '9' && i > 0:
-		case r == '_':
-		default:
-			return false
-		}
+// Store 附属存储, 独立于目标数据库, 用于保存查询历史等工具自身的数据
+var Store *sqlx.DB
+
+// ConnectStore 打开(不存在则创建)附属存储数据库
+func ConnectStore(path string) error {
+	instance, err := sqlx.Connect("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(5000)", path))
+	if err != nil {
+		return err
 	}
-	return true
+	Store = instance
+	return nil
 }
@@ -8,8 +8,10 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/fuxingjun/go-sqlite-web/app/routes"
+	"github.com/fuxingjun/go-sqlite-web/app/services"
 	"github.com/fuxingjun/go-sqlite-web/app/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -22,6 +24,7 @@ func setupRoutes(app *fiber.App) {
 	routes.AuthRoute(app)
 	routes.DatabaseRoute(app)
 	routes.TableRoute(app)
+	routes.SavedQueryRoute(app)
 }
 
 //go:embed vue3-sqlite-web/dist/*
@@ -50,12 +53,17 @@ func main() {
 	port := flag.Int("port", 12249, "Server port")
 	readonly := flag.Bool("readonly", false, "Open database in read-only mode")
 	debug := flag.Bool("debug", false, "Enable debug mode with detailed logging")
+	store := flag.String("store", "", "Sidecar store file for query history (default: <db>.web.sqlite)")
+	historyLimit := flag.Int("history-limit", 10000, "Max query history entries to keep, 0 for unlimited")
+	txTimeout := flag.Duration("tx-timeout", 5*time.Minute, "Idle timeout before an interactive transaction is rolled back")
+	foreignKeys := flag.Bool("foreign-keys", false, "Enforce foreign key constraints (PRAGMA foreign_keys) on every connection")
 
 	flag.Parse()
 
 	if _, err := os.Stat(*db); os.IsNotExist(err) {
 		log.Fatal("Database file does not exist: ", db)
 	}
+	utils.ForeignKeys = *foreignKeys
 	if err := utils.Connect(*db, *readonly); err != nil {
 		log.Fatal("DB connect error: ", err)
 	}
@@ -66,8 +74,21 @@ func main() {
 	}
 	utils.InitLogger(level, "", "logs", "midnight", 1)
 
+	// 附属存储独立于目标数据库, 打开失败时仅禁用相关功能
+	if *store == "" {
+		*store = *db + ".web.sqlite"
+	}
+	services.HistoryLimit = *historyLimit
+	services.IdleTxTimeout = *txTimeout
+	if err := services.InitStore(*store); err != nil {
+		utils.GetLogger("").Warn("store disabled", "path", *store, "error", err)
+	} else {
+		defer utils.Store.Close()
+	}
+
 	// 创建 Fiber 应用实例
-	app := fiber.New()
+	// 路径参数按解码后的值匹配, 表名、列名可以包含中文等需要转义的字符
+	app := fiber.New(fiber.Config{UnescapePath: true})
 	if *debug {
 		app.Use(logger.New())
 	}